@@ -0,0 +1,125 @@
+// aggregate.go
+// Repository-level view of code hits: one repo can yield dozens of files, so reports
+// list repositories (file count, languages, matched groups, newest commit) and keep
+// per-file detail for the findings JSON and the few files worth citing.
+
+package main
+
+import (
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const maxRepoRows = 25 // rows in the fallback report's repository table
+
+// RepoAggregate summarizes the code hits of one repository.
+type RepoAggregate struct {
+	Repository   string    `json:"repository"`
+	RepoURL      string    `json:"repoUrl"`
+	Files        int       `json:"files"`
+	NewFiles     int       `json:"newFiles"`
+	Languages    []string  `json:"languages"`
+	Groups       []string  `json:"groups"`
+	NewestCommit time.Time `json:"newestCommit"` // zero when no file was verified
+	Stars        int       `json:"stars"`        // -1 without repo metadata
+	Score        float64   `json:"score"`        // best file score
+}
+
+// extLanguages names a file's language by extension; the search API only reports the
+// repository's primary language, if that.
+var extLanguages = map[string]string{
+	".py": "Python", ".ipynb": "Jupyter Notebook", ".js": "JavaScript", ".mjs": "JavaScript", ".jsx": "JavaScript",
+	".ts": "TypeScript", ".tsx": "TypeScript", ".go": "Go", ".java": "Java", ".kt": "Kotlin", ".scala": "Scala",
+	".rb": "Ruby", ".rs": "Rust", ".cs": "C#", ".cpp": "C++", ".cc": "C++", ".hpp": "C++", ".c": "C", ".h": "C",
+	".php": "PHP", ".swift": "Swift", ".r": "R", ".jl": "Julia", ".ex": "Elixir", ".sh": "Shell",
+}
+
+func fileLanguage(h CodeHit) string {
+	if l, ok := extLanguages[strings.ToLower(path.Ext(h.FilePath))]; ok {
+		return l
+	}
+	return h.Language
+}
+
+// aggregateByRepo groups hits by repository, best-scoring repository first (then the
+// one with most files). Hits are expected merged and ranked.
+func aggregateByRepo(hits []CodeHit) []RepoAggregate {
+	idx := map[string]int{}
+	var out []RepoAggregate
+	for _, h := range hits {
+		i, ok := idx[h.Repository]
+		if !ok {
+			i = len(out)
+			idx[h.Repository] = i
+			out = append(out, RepoAggregate{Repository: h.Repository, RepoURL: h.RepoURL, Stars: -1, Score: h.Score})
+		}
+		a := &out[i]
+		a.Files++
+		if h.IsNew {
+			a.NewFiles++
+		}
+		if l := fileLanguage(h); l != "" {
+			a.Languages = addString(a.Languages, l)
+		}
+		for _, r := range h.MatchedBy {
+			a.Groups = addString(a.Groups, r.Group)
+		}
+		if h.CommitDate.After(a.NewestCommit) {
+			a.NewestCommit = h.CommitDate
+		}
+		if h.RepoMeta != nil {
+			a.Stars = h.RepoMeta.Stars
+		}
+		if h.Score > a.Score {
+			a.Score = h.Score
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Score != out[j].Score {
+			return out[i].Score > out[j].Score
+		}
+		return out[i].Files > out[j].Files
+	})
+	return out
+}
+
+func addString(list []string, s string) []string {
+	for _, x := range list {
+		if x == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
+
+// writeRepoTable renders up to maxRepoRows aggregates as a Markdown table.
+func writeRepoTable(b *strings.Builder, aggs []RepoAggregate) {
+	b.WriteString("Repositories with code hits:\n\n")
+	b.WriteString("| Repository | Files | Languages | Groups | Newest commit | Stars |\n")
+	b.WriteString("|---|---|---|---|---|---|\n")
+	for i, a := range aggs {
+		if i == maxRepoRows {
+			break
+		}
+		files := strconv.Itoa(a.Files)
+		if a.NewFiles > 0 {
+			files += " (" + strconv.Itoa(a.NewFiles) + " new)"
+		}
+		commit, stars := "—", "—"
+		if !a.NewestCommit.IsZero() {
+			commit = a.NewestCommit.Format("2006-01-02")
+		}
+		if a.Stars >= 0 {
+			stars = strconv.Itoa(a.Stars)
+		}
+		b.WriteString("| [" + a.Repository + "](" + a.RepoURL + ") | " + files + " | " + strings.Join(a.Languages, ", ") +
+			" | " + strings.ReplaceAll(strings.Join(a.Groups, ", "), "|", "\\|") + " | " + commit + " | " + stars + " |\n")
+	}
+	if len(aggs) > maxRepoRows {
+		b.WriteString("\n… and " + strconv.Itoa(len(aggs)-maxRepoRows) + " more repositories.\n")
+	}
+	b.WriteString("\n")
+}
@@ -0,0 +1,259 @@
+// auth.go
+// Where ghClient gets its bearer tokens: a static token (PAT) from the environment, a
+// pool of them (GITHUB_TOKENS) to rotate across, or a GitHub App installation token
+// minted from an app JWT and refreshed before expiry.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GitHub App settings, read from the environment like GITHUB_TOKEN.
+const (
+	envAppID           = "GITHUB_APP_ID"
+	envAppKey          = "GITHUB_APP_PRIVATE_KEY"      // PEM contents
+	envAppKeyFile      = "GITHUB_APP_PRIVATE_KEY_FILE" // or a path to the PEM
+	envAppInstallation = "GITHUB_APP_INSTALLATION_ID"  // optional when the app has a single installation
+)
+
+// envTokens lists several static tokens (comma or whitespace separated); requests go to
+// whichever has the most budget left. Takes precedence over GITHUB_TOKEN.
+const envTokens = "GITHUB_TOKENS"
+
+// refreshBefore renews an installation token this long before it expires.
+const refreshBefore = 5 * time.Minute
+
+// tokenSource yields the bearer token for a request; "" means unauthenticated.
+type tokenSource interface {
+	token(ctx context.Context) (string, error)
+	// name identifies the credential in rate-limit reports without revealing it.
+	name() string
+}
+
+type envToken string // the variable holding a static token
+
+func (e envToken) token(context.Context) (string, error) { return os.Getenv(string(e)), nil }
+func (e envToken) name() string                          { return string(e) }
+
+// poolToken is one entry of GITHUB_TOKENS, named by position.
+type poolToken struct{ label, value string }
+
+func (p poolToken) token(context.Context) (string, error) { return p.value, nil }
+func (p poolToken) name() string                          { return p.label }
+
+// poolTokens splits GITHUB_TOKENS, dropping duplicates.
+func poolTokens() []tokenSource {
+	var out []tokenSource
+	seen := map[string]bool{}
+	for _, t := range strings.FieldsFunc(os.Getenv(envTokens), func(r rune) bool { return r == ',' || r == ' ' || r == '\n' || r == '\t' }) {
+		if !seen[t] {
+			seen[t] = true
+			out = append(out, poolToken{label: fmt.Sprintf("%s#%d", envTokens, len(out)+1), value: t})
+		}
+	}
+	return out
+}
+
+// appTokenSource exchanges a GitHub App JWT for an installation token and caches it.
+type appTokenSource struct {
+	apiBase        string
+	appID          string
+	installationID string
+	key            *rsa.PrivateKey
+
+	mu      sync.Mutex
+	tok     string
+	expires time.Time
+}
+
+func (a *appTokenSource) name() string { return "app " + a.appID }
+
+func (a *appTokenSource) token(ctx context.Context) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.tok != "" && time.Until(a.expires) > refreshBefore {
+		return a.tok, nil
+	}
+	if a.installationID == "" {
+		id, err := a.soleInstallation(ctx)
+		if err != nil {
+			return "", err
+		}
+		a.installationID = id
+	}
+	var out struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err := a.appRequest(ctx, "POST", "/app/installations/"+a.installationID+"/access_tokens", &out); err != nil {
+		return "", fmt.Errorf("github app: installation token: %w", err)
+	}
+	a.tok, a.expires = out.Token, out.ExpiresAt
+	return a.tok, nil
+}
+
+// soleInstallation finds the installation to use when none is configured.
+func (a *appTokenSource) soleInstallation(ctx context.Context) (string, error) {
+	var insts []struct {
+		ID      int64 `json:"id"`
+		Account struct {
+			Login string `json:"login"`
+		} `json:"account"`
+	}
+	if err := a.appRequest(ctx, "GET", "/app/installations", &insts); err != nil {
+		return "", fmt.Errorf("github app: list installations: %w", err)
+	}
+	switch len(insts) {
+	case 0:
+		return "", errors.New("github app: no installations")
+	case 1:
+		return strconv.FormatInt(insts[0].ID, 10), nil
+	default:
+		return "", fmt.Errorf("github app: %d installations; set %s", len(insts), envAppInstallation)
+	}
+}
+
+// appRequest calls an /app endpoint authenticated with a fresh app JWT.
+func (a *appTokenSource) appRequest(ctx context.Context, method, path string, out any) error {
+	jwt, err := a.jwt(time.Now())
+	if err != nil {
+		return err
+	}
+	req, _ := http.NewRequestWithContext(ctx, method, a.apiBase+path, nil)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("status %d: %s", resp.StatusCode, truncate(string(body), 200))
+	}
+	return json.Unmarshal(body, out)
+}
+
+// jwt signs the RS256 app token GitHub expects: issued a minute in the past to absorb
+// clock drift, valid for nine of the allowed ten minutes.
+func (a *appTokenSource) jwt(now time.Time) (string, error) {
+	enc := base64.RawURLEncoding
+	header := enc.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	claims, _ := json.Marshal(map[string]any{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": a.appID,
+	})
+	signing := header + "." + enc.EncodeToString(claims)
+	sum := sha256.Sum256([]byte(signing))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, a.key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", err
+	}
+	return signing + "." + enc.EncodeToString(sig), nil
+}
+
+func parseRSAKey(pemBytes []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(bytes.TrimSpace(pemBytes))
+	if block == nil {
+		return nil, errors.New("no PEM block in private key")
+	}
+	if k, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return k, nil
+	}
+	k, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rk, ok := k.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not RSA")
+	}
+	return rk, nil
+}
+
+// newAppTokenSource reads the GITHUB_APP_* variables; it returns nil, nil when no app is configured.
+func newAppTokenSource(apiBase string) (*appTokenSource, error) {
+	appID := strings.TrimSpace(os.Getenv(envAppID))
+	if appID == "" {
+		return nil, nil
+	}
+	pemBytes := []byte(os.Getenv(envAppKey))
+	if path := os.Getenv(envAppKeyFile); len(bytes.TrimSpace(pemBytes)) == 0 && path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", envAppKeyFile, err)
+		}
+		pemBytes = b
+	}
+	if len(bytes.TrimSpace(pemBytes)) == 0 {
+		return nil, fmt.Errorf("%s is set but neither %s nor %s is", envAppID, envAppKey, envAppKeyFile)
+	}
+	// keys pasted into .env often carry literal \n
+	pemBytes = bytes.ReplaceAll(pemBytes, []byte(`\n`), []byte("\n"))
+	key, err := parseRSAKey(pemBytes)
+	if err != nil {
+		return nil, fmt.Errorf("github app private key: %w", err)
+	}
+	return &appTokenSource{apiBase: apiBase, appID: appID, installationID: strings.TrimSpace(os.Getenv(envAppInstallation)), key: key}, nil
+}
+
+var (
+	defaultAuthOnce sync.Once
+	defaultAuth     []tokenSource
+	defaultAuthErr  error
+)
+
+// defaultTokenSources are the github.com credentials: the GitHub App when GITHUB_APP_ID
+// is set, else the GITHUB_TOKENS pool, else GITHUB_TOKEN. They are built once so
+// installation tokens outlive a run.
+func defaultTokenSources() ([]tokenSource, error) {
+	defaultAuthOnce.Do(func() {
+		app, err := newAppTokenSource(defaultAPIBase)
+		switch {
+		case err != nil:
+			defaultAuthErr = err
+		case app != nil:
+			defaultAuth = []tokenSource{app}
+		default:
+			defaultAuth = poolTokens()
+			if len(defaultAuth) == 0 {
+				defaultAuth = []tokenSource{envToken(defaultTokenEnv)}
+			}
+		}
+	})
+	return defaultAuth, defaultAuthErr
+}
+
+// githubAuthProblem says why no github.com credential is available, or "".
+func githubAuthProblem() string {
+	if os.Getenv(envAppID) != "" {
+		if _, err := newAppTokenSource(defaultAPIBase); err != nil {
+			return err.Error()
+		}
+		return ""
+	}
+	if os.Getenv(defaultTokenEnv) == "" && len(poolTokens()) == 0 {
+		return "GITHUB_TOKEN (or GITHUB_TOKENS, or GITHUB_APP_ID + private key) is not set"
+	}
+	return ""
+}
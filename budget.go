@@ -0,0 +1,61 @@
+// budget.go
+// The search phase's time budget. runReport sizes it from the number of searches, but a
+// windowed search that bisects its date range adds requests nobody counted, so the
+// deadline moves out by the per-request allowance for each of them, up to a hard cap.
+
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// maxSearchBudget caps the search phase however many requests window splits add.
+const maxSearchBudget = 20 * time.Minute
+
+type runBudget struct {
+	mu       sync.Mutex
+	deadline time.Time
+	limit    time.Time // extensions stop here
+	perReq   time.Duration
+	timer    *time.Timer
+}
+
+// withRunBudget returns a context cancelled with cause context.DeadlineExceeded once the
+// budget d (plus any extensions) has passed.
+func withRunBudget(parent context.Context, d, perReq time.Duration) (context.Context, *runBudget, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(parent)
+	now := time.Now()
+	b := &runBudget{deadline: now.Add(d), limit: now.Add(maxSearchBudget), perReq: perReq}
+	if d > maxSearchBudget {
+		b.limit = b.deadline
+	}
+	b.timer = time.AfterFunc(d, func() { cancel(context.DeadlineExceeded) })
+	return ctx, b, func() {
+		b.timer.Stop()
+		cancel(context.Canceled)
+	}
+}
+
+// addRequests extends the deadline for n more requests. A nil budget or one that has
+// already run out is left alone.
+func (b *runBudget) addRequests(n int) {
+	if b == nil || n <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	if !b.deadline.After(now) {
+		return
+	}
+	next := b.deadline.Add(time.Duration(n) * b.perReq)
+	if next.After(b.limit) {
+		next = b.limit
+	}
+	if next.After(b.deadline) && b.timer.Stop() {
+		b.deadline = next
+		b.timer.Reset(next.Sub(now))
+	}
+}
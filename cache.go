@@ -0,0 +1,135 @@
+// cache.go
+// On-disk HTTP cache for GitHub GETs: the last 200 body per URL with its ETag /
+// Last-Modified, revalidated with If-None-Match / If-Modified-Since. GitHub doesn't
+// count 304s against the rate limit, so repeat commit-recency lookups become free.
+// Entries not used for cacheMaxAge are pruned at the start of each run.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const defaultCacheDir = "httpcache"
+
+// cacheMaxAge is how long an entry may go unused before prune removes it; a file that
+// stops matching any search would otherwise keep its entry forever.
+const cacheMaxAge = 30 * 24 * time.Hour
+
+type cacheEntry struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+	Body         string `json:"body"`
+}
+
+type httpCache struct {
+	dir          string
+	hits, misses atomic.Int64
+}
+
+func newHTTPCache(dir string) *httpCache {
+	return &httpCache{dir: dir}
+}
+
+func (c *httpCache) path(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:16])+".json")
+}
+
+// lookup returns the stored entry for url, or nil.
+func (c *httpCache) lookup(url string) *cacheEntry {
+	var e cacheEntry
+	if err := readJSONFile(c.path(url), &e); err != nil || e.URL != url {
+		return nil
+	}
+	return &e
+}
+
+// condition adds the validators of e to req.
+func (e *cacheEntry) condition(req *http.Request) {
+	if e.ETag != "" {
+		req.Header.Set("If-None-Match", e.ETag)
+	}
+	if e.LastModified != "" {
+		req.Header.Set("If-Modified-Since", e.LastModified)
+	}
+}
+
+// store keeps a 200 body that came with a validator. Write-then-rename, like the
+// seen store, so concurrent workers never read a half-written entry.
+func (c *httpCache) store(url string, resp *http.Response, body []byte) error {
+	e := cacheEntry{URL: url, ETag: resp.Header.Get("ETag"), LastModified: resp.Header.Get("Last-Modified"), Body: string(body)}
+	if e.ETag == "" && e.LastModified == "" {
+		return nil
+	}
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(c.dir, "tmp-*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	_ = f.Close()
+	if err := writeJSONFile(tmp, e); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, c.path(url))
+}
+
+// touch marks the entry for url as used, so prune keeps it.
+func (c *httpCache) touch(url string) {
+	now := time.Now()
+	_ = os.Chtimes(c.path(url), now, now)
+}
+
+// prune removes entries (and leftover temp files) not written or touched within
+// maxAge, and returns how many it removed. A missing directory is not an error.
+func (c *httpCache) prune(maxAge time.Duration) (int, error) {
+	entries, err := os.ReadDir(c.dir)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	cutoff := time.Now().Add(-maxAge)
+	removed := 0
+	for _, de := range entries {
+		if de.IsDir() {
+			continue
+		}
+		info, err := de.Info()
+		if err != nil || !info.ModTime().Before(cutoff) {
+			continue
+		}
+		if os.Remove(filepath.Join(c.dir, de.Name())) == nil {
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// String is the hit/miss summary for the run's notes.
+func (c *httpCache) String() string {
+	return fmt.Sprintf("HTTP cache: %d hits (not counted against the rate limit), %d misses", c.hits.Load(), c.misses.Load())
+}
+
+// revalidated turns a 304 into the cached 200, keeping the 304's (rate-limit) headers.
+func (e *cacheEntry) revalidated(resp *http.Response) *http.Response {
+	out := *resp
+	out.StatusCode, out.Status = 200, "200 OK (cached)"
+	out.Body = io.NopCloser(strings.NewReader(e.Body))
+	return &out
+}
@@ -0,0 +1,304 @@
+// cli.go
+// Subcommands: `serve` (web UI, the default), `run` (headless report for cron/CI) and `validate`.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Exit codes for the headless commands.
+const (
+	exitOK       = 0
+	exitFailed   = 1 // the search phase failed or the run was interrupted
+	exitUsage    = 2 // bad flags, settings, queries or missing keys
+	exitFallback = 3 // report written, but OpenAI drafting failed and the fallback report was used
+)
+
+const usageText = `usage: gh-api-watch [command] [flags]
+
+commands:
+  serve      start the local web UI (default)
+  run        run one report headlessly and write Markdown/JSON
+  validate   check settings, queries.yaml and environment without searching
+
+Run "gh-api-watch <command> -h" for command flags.
+`
+
+func runCLI(args []string) int {
+	cmd := "serve"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		cmd, args = args[0], args[1:]
+	}
+	switch cmd {
+	case "serve":
+		return cmdServe(args)
+	case "run":
+		return cmdRun(args)
+	case "validate":
+		return cmdValidate(args)
+	case "help":
+		fmt.Fprint(os.Stdout, usageText)
+		return exitOK
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", cmd, usageText)
+		return exitUsage
+	}
+}
+
+func cmdServe(args []string) int {
+	cfg, opts, err := resolveSettings("serve", args, func(fs *flag.FlagSet, o *runOptions) {
+		fs.StringVar(&o.port, "port", o.port, "port to listen on (127.0.0.1)")
+		fs.BoolVar(&o.noOpen, "no-open", o.noOpen, "don't open the browser")
+	})
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	if err := serve(cfg, opts); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailed
+	}
+	return exitOK
+}
+
+// runOptions are the command flags that are not AppSettings.
+type runOptions struct {
+	settingsFile string
+	settingsSet  bool // the settings file existed, or GHW_* variables were applied
+	out          string
+	jsonOut      string
+	quiet        bool
+	port         string
+	noOpen       bool
+}
+
+func defaultRunOptions() runOptions {
+	return runOptions{settingsFile: os.Getenv("GHW_SETTINGS_FILE"), out: "-", port: envOr("PORT", defaultPort)}
+}
+
+// bindSettingsFlags registers one flag per AppSettings field, defaulting to cfg's current values.
+func bindSettingsFlags(fs *flag.FlagSet, cfg *AppSettings, opts *runOptions) {
+	fs.StringVar(&opts.settingsFile, "settings", opts.settingsFile, "JSON settings file (the one the UI's Save settings writes; default settings.json next to the queries file)")
+	fs.IntVar(&cfg.DaysBack, "days", cfg.DaysBack, "days back")
+	fs.StringVar(&cfg.OpenAIModel, "model", cfg.OpenAIModel, "OpenAI model")
+	fs.IntVar(&cfg.MaxPages, "max-pages", cfg.MaxPages, "max pages per query")
+	fs.IntVar(&cfg.PerPage, "per-page", cfg.PerPage, "items per page")
+	fs.BoolVar(&cfg.UseCommitCheck, "commit-check", cfg.UseCommitCheck, "verify file recency via Commits API")
+	fs.StringVar(&cfg.CommitCheckMode, "commit-check-mode", cfg.CommitCheckMode, "recency lookups: graphql (batched) or rest")
+	fs.BoolVar(&cfg.EnrichRepos, "repo-meta", cfg.EnrichRepos, "fetch repository metadata for code hits")
+	fs.Func("score-weights", "relevance weights, e.g. recency=1,stars=1,queries=1,path=1,org=0.25 (now "+cfg.Scoring.String()+")",
+		func(v string) error { return parseScoreWeights(v, &cfg.Scoring) })
+	fs.BoolVar(&cfg.IncludeRepoSearch, "repo-search", cfg.IncludeRepoSearch, "include repo (README/desc) searches")
+	fs.StringVar(&cfg.QueriesFile, "queries", cfg.QueriesFile, "queries file")
+	fs.StringVar(&cfg.SeenFile, "seen", cfg.SeenFile, "seen-hits store")
+	fs.BoolVar(&cfg.NewOnly, "new-only", cfg.NewOnly, "report only first-seen hits")
+	fs.BoolVar(&cfg.SkipOpenAI, "no-openai", cfg.SkipOpenAI, "skip OpenAI drafting and write the fallback report")
+	fs.StringVar(&cfg.RunsDir, "runs", cfg.RunsDir, "run history directory")
+	fs.StringVar(&cfg.CacheDir, "cache", cfg.CacheDir, "HTTP cache directory for GitHub requests")
+}
+
+// resolveSettings resolves settings as defaults < settings file < GHW_* env < explicit flags.
+// Flags are parsed twice: once to find -settings, then again on top of the file and env.
+// Without -settings or GHW_SETTINGS_FILE the file is settings.json next to the queries
+// file named by -queries or GHW_QUERIES_FILE (the working directory by default).
+// A missing settings file is only an error when -settings named it explicitly.
+func resolveSettings(name string, args []string, extra func(*flag.FlagSet, *runOptions)) (AppSettings, runOptions, error) {
+	parse := func(cfg *AppSettings, opts *runOptions, silent bool) (*flag.FlagSet, error) {
+		fs := flag.NewFlagSet(name, flag.ContinueOnError)
+		if silent {
+			fs.SetOutput(io.Discard)
+		}
+		bindSettingsFlags(fs, cfg, opts)
+		if extra != nil {
+			extra(fs, opts)
+		}
+		return fs, fs.Parse(args)
+	}
+
+	cfg, opts := defaultSettings(), defaultRunOptions()
+	fs, err := parse(&cfg, &opts, false)
+	if err != nil {
+		return cfg, opts, err
+	}
+	path, explicit, queriesFlag := opts.settingsFile, false, false
+	fs.Visit(func(f *flag.Flag) {
+		explicit = explicit || f.Name == "settings"
+		queriesFlag = queriesFlag || f.Name == "queries"
+	})
+	if path == "" {
+		queries := cfg.QueriesFile
+		if v := os.Getenv("GHW_QUERIES_FILE"); v != "" && !queriesFlag {
+			queries = v
+		}
+		path = settingsFileFor(queries)
+	}
+
+	cfg, found, err := loadSettingsFile(path)
+	if err != nil {
+		return cfg, opts, err
+	}
+	if !found && explicit {
+		return cfg, opts, fmt.Errorf("%s: %w", path, os.ErrNotExist)
+	}
+	nEnv, err := applySettingsEnv(&cfg)
+	if err != nil {
+		return cfg, opts, err
+	}
+	opts = defaultRunOptions()
+	if _, err := parse(&cfg, &opts, true); err != nil {
+		return cfg, opts, err
+	}
+	opts.settingsFile = path
+	opts.settingsSet = found || nEnv > 0
+	cfg.normalize()
+	return cfg, opts, nil
+}
+
+func cmdRun(args []string) int {
+	cfg, opts, err := resolveSettings("run", args, func(fs *flag.FlagSet, o *runOptions) {
+		fs.StringVar(&o.out, "out", o.out, `Markdown output file ("-" for stdout)`)
+		fs.StringVar(&o.jsonOut, "json", o.jsonOut, `findings JSON output file ("-" for stdout; empty to skip)`)
+		fs.BoolVar(&o.quiet, "quiet", o.quiet, "don't log debug events to stderr")
+	})
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	if p := githubAuthProblem(); p != "" {
+		fmt.Fprintln(os.Stderr, "GitHub auth: "+p)
+		return exitUsage
+	}
+	if !cfg.SkipOpenAI && os.Getenv("OPENAI_API_KEY") == "" {
+		fmt.Fprintln(os.Stderr, "Missing OPENAI_API_KEY in environment or .env (use -no-openai to skip drafting)")
+		return exitUsage
+	}
+	spec, err := loadQueries(cfg.QueriesFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", cfg.QueriesFile, err)
+		return exitUsage
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	runID := newRunID()
+	var events []DebugEvent
+	emit := func(ev DebugEvent) {
+		ev.TS = time.Now().Format(time.RFC3339)
+		ev.RunID = runID
+		events = append(events, ev)
+		if !opts.quiet {
+			logEvent(ev)
+		}
+	}
+	started := time.Now()
+	res, err := runReport(ctx, cfg, spec, runID, emit, nil)
+	recordCLIRun(cfg, runID, started, res, err, events)
+	if err != nil && !errors.Is(err, errRunCancelled) {
+		fmt.Fprintln(os.Stderr, "search error:", err)
+		return exitFailed
+	}
+
+	if err := writeOutput(opts.out, []byte(res.Markdown)); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailed
+	}
+	if opts.jsonOut != "" {
+		b, _ := json.MarshalIndent(res.Findings, "", "  ")
+		if err := writeOutput(opts.jsonOut, append(b, '\n')); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitFailed
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "run cancelled; partial report written")
+		return exitFailed
+	}
+	if res.Fallback && !cfg.SkipOpenAI {
+		return exitFallback
+	}
+	return exitOK
+}
+
+func cmdValidate(args []string) int {
+	cfg, _, err := resolveSettings("validate", args, nil)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	var problems []string
+	if p := githubAuthProblem(); p != "" {
+		problems = append(problems, p)
+	}
+	if !cfg.SkipOpenAI && os.Getenv("OPENAI_API_KEY") == "" {
+		problems = append(problems, "OPENAI_API_KEY is not set")
+	}
+	spec, err := loadQueries(cfg.QueriesFile)
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("%s: %v", cfg.QueriesFile, err))
+	} else {
+		for _, p := range validateQueries(spec) {
+			problems = append(problems, cfg.QueriesFile+": "+p)
+		}
+	}
+	if len(problems) > 0 {
+		for _, p := range problems {
+			fmt.Fprintln(os.Stderr, "- "+p)
+		}
+		return exitUsage
+	}
+	fmt.Fprintf(os.Stdout, "ok: %s (%d groups)\n", cfg.QueriesFile, len(spec.Groups))
+	return exitOK
+}
+
+func writeOutput(path string, b []byte) error {
+	if path == "" || path == "-" {
+		_, err := os.Stdout.Write(b)
+		return err
+	}
+	return os.WriteFile(path, b, 0644)
+}
+
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// recordCLIRun stores a headless run in the same history the web UI browses.
+func recordCLIRun(cfg AppSettings, runID string, started time.Time, res reportResult, err error, events []DebugEvent) {
+	rec := runResult{ID: runID, Trigger: "cli", Started: started, Finished: time.Now(),
+		Markdown: res.Markdown, Findings: res.Findings, Fallback: res.Fallback}
+	switch {
+	case errors.Is(err, errRunCancelled):
+		rec.State, rec.Status = runCancelled, "Cancelled."
+	case err != nil:
+		rec.State, rec.Status, rec.Error = runFailed, "Failed: "+err.Error(), err.Error()
+	default:
+		rec.State, rec.Status = runDone, "Done."
+	}
+	if err := (historyStore{dir: cfg.RunsDir}).save(rec, cfg, events); err != nil {
+		fmt.Fprintln(os.Stderr, "run history:", err)
+	}
+}
@@ -0,0 +1,191 @@
+// commitcheck.go
+// Batched commit-recency lookups over GraphQL: one query asks for the latest commit of
+// up to gqlBatchSize files, each as an aliased repository field. A batch that fails as
+// a whole is redone with the per-file REST lookups of enrichWithCommitDates.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Commit check modes (AppSettings.CommitCheckMode).
+const (
+	commitCheckGraphQL = "graphql"
+	commitCheckREST    = "rest"
+)
+
+// gqlBatchSize is how many files one GraphQL query looks up; each costs next to nothing
+// (history(first: 1)), the limit is keeping the query and response small.
+const gqlBatchSize = 50
+
+// historyResp is one aliased repository field of a batch.
+type historyResp struct {
+	DefaultBranchRef *struct {
+		Target struct {
+			History struct {
+				Nodes []struct {
+					AuthoredDate string `json:"authoredDate"`
+				} `json:"nodes"`
+			} `json:"history"`
+		} `json:"target"`
+	} `json:"defaultBranchRef"`
+}
+
+type graphqlResp struct {
+	Data   map[string]*historyResp `json:"data"`
+	Errors []struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// enrichWithCommitDatesGraphQL is enrichWithCommitDates in batches. Batches never mix
+// hosts: hits are grouped by the client clientFor picks for them. GraphQL has no
+// conditional requests, so files whose REST lookup is in the HTTP cache are revalidated
+// over REST instead, where a 304 costs nothing.
+func enrichWithCommitDatesGraphQL(ctx context.Context, clientFor func(CodeHit) *ghClient, since time.Time, hits []CodeHit, emit func(DebugEvent)) []CodeHit {
+	type batch struct {
+		c   *ghClient
+		idx []int // into hits
+	}
+	out := make([]CodeHit, len(hits))
+	copy(out, hits)
+	var cachedIdx []int
+	var cached []CodeHit
+	byClient := map[*ghClient][]int{}
+	var clients []*ghClient
+	for i, h := range hits {
+		c := clientFor(h)
+		if c.cache.lookup(c.commitsURL(h.Repository, h.FilePath, 1)) != nil {
+			cachedIdx = append(cachedIdx, i)
+			cached = append(cached, h)
+			continue
+		}
+		if _, ok := byClient[c]; !ok {
+			clients = append(clients, c)
+		}
+		byClient[c] = append(byClient[c], i)
+	}
+	var batches []batch
+	for _, c := range clients {
+		for idx := byClient[c]; len(idx) > 0; {
+			n := min(len(idx), gqlBatchSize)
+			batches = append(batches, batch{c, idx[:n]})
+			idx = idx[n:]
+		}
+	}
+
+	if len(cached) > 0 {
+		emit(DebugEvent{Phase: "commit-check-cached", Note: fmt.Sprintf("files=%d", len(cached))})
+		for k, h := range enrichWithCommitDates(ctx, clientFor, since, cached) {
+			out[cachedIdx[k]].CommitDate = h.CommitDate
+		}
+	}
+
+	jobs := make(chan batch)
+	wg := sync.WaitGroup{}
+	worker := func() {
+		defer wg.Done()
+		for b := range jobs {
+			sub := make([]CodeHit, len(b.idx))
+			for k, i := range b.idx {
+				sub[k] = hits[i]
+			}
+			dates, err := commitDatesGraphQL(ctx, b.c, since, sub, emit)
+			if err != nil {
+				if ctx.Err() != nil {
+					continue
+				}
+				emit(DebugEvent{Phase: "commit-check-fallback", URL: b.c.graphqlURL(), Note: fmt.Sprintf("files=%d: %v", len(sub), err)})
+				rest := enrichWithCommitDates(ctx, func(CodeHit) *ghClient { return b.c }, since, sub)
+				for k := range rest {
+					dates = append(dates, rest[k].CommitDate)
+				}
+			}
+			// each batch owns its indices, so no locking
+			for k, i := range b.idx {
+				out[i].CommitDate = dates[k]
+			}
+		}
+	}
+
+	wg.Add(maxConcurrentDetails)
+	for k := 0; k < maxConcurrentDetails; k++ {
+		go worker()
+	}
+	for _, b := range batches {
+		jobs <- b
+	}
+	close(jobs)
+	wg.Wait()
+	return out
+}
+
+// commitDatesGraphQL returns the latest commit date since `since` of each hit's file on
+// its repository's default branch, zero where there is none or the repository is gone
+// (as with REST). An error means the batch as a whole failed.
+func commitDatesGraphQL(ctx context.Context, c *ghClient, since time.Time, hits []CodeHit, emit func(DebugEvent)) ([]time.Time, error) {
+	var q strings.Builder
+	vars := map[string]any{"since": since.Format(time.RFC3339)}
+	q.WriteString("query($since: GitTimestamp!")
+	for k := range hits {
+		fmt.Fprintf(&q, ", $o%d: String!, $n%d: String!, $p%d: String!", k, k, k)
+	}
+	q.WriteString(") {")
+	for k, h := range hits {
+		owner, name, _ := strings.Cut(h.Repository, "/")
+		vars[fmt.Sprintf("o%d", k)], vars[fmt.Sprintf("n%d", k)], vars[fmt.Sprintf("p%d", k)] = owner, name, h.FilePath
+		fmt.Fprintf(&q, " h%d: repository(owner: $o%d, name: $n%d) { defaultBranchRef { target { ... on Commit {"+
+			" history(first: 1, path: $p%d, since: $since) { nodes { authoredDate } } } } } }", k, k, k, k)
+	}
+	q.WriteString(" }")
+	payload, _ := json.Marshal(map[string]any{"query": q.String(), "variables": vars})
+
+	url := c.graphqlURL()
+	resp, err := c.post(ctx, url, payload)
+	if err != nil {
+		return nil, err
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("%s: %s", resp.Status, truncate(string(body), 200))
+	}
+	var gr graphqlResp
+	if err := json.Unmarshal(body, &gr); err != nil {
+		return nil, err
+	}
+	if gr.Data == nil {
+		msg := "no data"
+		if len(gr.Errors) > 0 {
+			msg = gr.Errors[0].Message
+		}
+		return nil, errors.New(msg)
+	}
+
+	dates := make([]time.Time, len(hits))
+	found := 0
+	for k := range hits {
+		r := gr.Data[fmt.Sprintf("h%d", k)]
+		if r == nil || r.DefaultBranchRef == nil {
+			continue
+		}
+		if nodes := r.DefaultBranchRef.Target.History.Nodes; len(nodes) > 0 {
+			dates[k], _ = time.Parse(time.RFC3339, nodes[0].AuthoredDate)
+			found++
+		}
+	}
+	// per-alias errors (NOT_FOUND for a deleted repo) leave that file unverified
+	emit(DebugEvent{Phase: "commit-check-graphql", URL: url, Status: resp.StatusCode,
+		RateRemaining: resp.Header.Get("X-RateLimit-Remaining"), RateReset: resp.Header.Get("X-RateLimit-Reset"),
+		Note: fmt.Sprintf("files=%d recent=%d errors=%d", len(hits), found, len(gr.Errors))})
+	return dates, nil
+}
@@ -0,0 +1,100 @@
+// commits.go
+// `type: commits` searches: GitHub commit search over committer-date windows.
+// Commit messages like "add alpaca integration" are a direct adoption signal.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type CommitHit struct {
+	Group       string     `json:"group"`
+	QueryName   string     `json:"queryName"`
+	Repository  string     `json:"repository"`
+	RepoURL     string     `json:"repoUrl"`
+	SHA         string     `json:"sha"`
+	CommitURL   string     `json:"commitUrl"`
+	Message     string     `json:"message"` // first line only
+	Author      string     `json:"author"`
+	CommittedAt time.Time  `json:"committedAt"`
+	FirstSeen   time.Time  `json:"firstSeen"`
+	LastSeen    time.Time  `json:"lastSeen"`
+	IsNew       bool       `json:"isNew"`
+	Score       float64    `json:"score"`
+	MatchedBy   []QueryRef `json:"matchedBy"`
+}
+
+type commitSearchResp struct {
+	TotalCount        int          `json:"total_count"`
+	IncompleteResults bool         `json:"incomplete_results"`
+	Items             []commitItem `json:"items"`
+}
+type commitItem struct {
+	SHA     string `json:"sha"`
+	HTMLURL string `json:"html_url"`
+	Commit  struct {
+		Message string `json:"message"`
+		Author  struct {
+			Name string `json:"name"`
+		} `json:"author"`
+		Committer struct {
+			Date string `json:"date"`
+		} `json:"committer"`
+	} `json:"commit"`
+	Author *struct {
+		Login string `json:"login"`
+	} `json:"author"`
+	Repository codeRepo `json:"repository"`
+}
+
+// searchCommits runs a commit query over committer-date slices; see windowedSearch.
+func searchCommits(ctx context.Context, client *ghClient, cfg AppSettings, g SearchGroup, q SearchQuery, since time.Time, emit func(DebugEvent)) ([]CommitHit, []string, error) {
+	var hits []CommitHit
+	ws := windowedSearch{kind: "commits", endpoint: "commits", sort: "committer-date", field: "committer-date", query: q.Query,
+		decode: func(body []byte) (int, bool, int, error) {
+			var cr commitSearchResp
+			if err := json.Unmarshal(body, &cr); err != nil {
+				return 0, false, 0, err
+			}
+			for _, it := range cr.Items {
+				committed, _ := time.Parse(time.RFC3339, it.Commit.Committer.Date)
+				if committed.Before(since) {
+					continue
+				}
+				author := it.Commit.Author.Name
+				if it.Author != nil && it.Author.Login != "" {
+					author = it.Author.Login
+				}
+				msg, _, _ := strings.Cut(strings.TrimSpace(it.Commit.Message), "\n")
+				hits = append(hits, CommitHit{
+					Group:       g.Name,
+					QueryName:   q.Name,
+					Repository:  it.Repository.FullName,
+					RepoURL:     it.Repository.HTMLURL,
+					SHA:         it.SHA,
+					CommitURL:   it.HTMLURL,
+					Message:     truncate(msg, 200),
+					Author:      author,
+					CommittedAt: committed,
+				})
+			}
+			return cr.TotalCount, cr.IncompleteResults, len(cr.Items), nil
+		}}
+	notes, err := ws.run(ctx, client, cfg, g, q, since, emit)
+	if err == nil && len(hits) == 0 {
+		notes = append(notes, fmt.Sprintf("No commit hits for %s — %s", g.Name, q.Name))
+		emit(DebugEvent{Phase: "search-commits-empty", Group: g.Name, QueryName: q.Name, Note: "no commit hits"})
+	}
+	return hits, notes, err
+}
+
+func dedupeCommit(in []CommitHit) []CommitHit {
+	return mergeHits(in, commitKey, func(h *CommitHit) (*[]QueryRef, QueryRef) { return &h.MatchedBy, QueryRef{h.Group, h.QueryName} }, nil)
+}
+
+func commitKey(h CommitHit) string { return h.Repository + "@" + h.SHA }
@@ -0,0 +1,140 @@
+// config.go
+// Persistent settings: settings.json next to queries.yaml, overridable per field by
+// GHW_* environment variables and command-line flags (defaults < file < env < flags).
+// Relative paths in the file, and the default ones, are relative to the file's
+// directory, so the working directory a command starts in doesn't matter.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const defaultSettingsFile = "settings.json"
+
+// settingsFileFor is the settings file beside queriesFile.
+func settingsFileFor(queriesFile string) string {
+	return filepath.Join(filepath.Dir(queriesFile), defaultSettingsFile)
+}
+
+// loadSettingsFile reads path on top of the defaults; found is false when it doesn't exist.
+func loadSettingsFile(path string) (cfg AppSettings, found bool, err error) {
+	cfg = defaultSettings()
+	if err := readJSONFile(path, &cfg); err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return cfg, false, fmt.Errorf("%s: %w", path, err)
+		}
+		cfg = defaultSettings()
+	} else {
+		found = true
+	}
+	dir := filepath.Dir(path)
+	for _, p := range settingsPaths(&cfg) {
+		if *p != "" && !filepath.IsAbs(*p) {
+			*p = filepath.Join(dir, *p)
+		}
+	}
+	return cfg, found, nil
+}
+
+// settingsPaths lists the AppSettings fields that name files or directories.
+func settingsPaths(c *AppSettings) []*string {
+	return []*string{&c.QueriesFile, &c.SeenFile, &c.RunsDir, &c.CacheDir}
+}
+
+func saveSettingsFile(path string, cfg AppSettings) error {
+	// SkipOpenAI is a per-invocation switch, not something the UI should persist
+	cfg.SkipOpenAI = false
+	// paths inside the file's directory are stored relative to it, others absolute
+	if dir, err := filepath.Abs(filepath.Dir(path)); err == nil {
+		for _, p := range settingsPaths(&cfg) {
+			abs, err := filepath.Abs(*p)
+			if *p == "" || err != nil {
+				continue
+			}
+			*p = abs
+			if rel, err := filepath.Rel(dir, abs); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				*p = rel
+			}
+		}
+	}
+	tmp := path + ".tmp"
+	if err := writeJSONFile(tmp, cfg); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// settingsEnv maps GHW_* variables onto AppSettings fields.
+var settingsEnv = []struct {
+	key   string
+	apply func(c *AppSettings, v string) error
+}{
+	{"GHW_DAYS_BACK", func(c *AppSettings, v string) error { return setInt(&c.DaysBack, v) }},
+	{"GHW_OPENAI_MODEL", func(c *AppSettings, v string) error { c.OpenAIModel = v; return nil }},
+	{"GHW_MAX_PAGES", func(c *AppSettings, v string) error { return setInt(&c.MaxPages, v) }},
+	{"GHW_PER_PAGE", func(c *AppSettings, v string) error { return setInt(&c.PerPage, v) }},
+	{"GHW_COMMIT_CHECK", func(c *AppSettings, v string) error { return setBool(&c.UseCommitCheck, v) }},
+	{"GHW_COMMIT_CHECK_MODE", func(c *AppSettings, v string) error { c.CommitCheckMode = v; return nil }},
+	{"GHW_REPO_META", func(c *AppSettings, v string) error { return setBool(&c.EnrichRepos, v) }},
+	{"GHW_SCORE_WEIGHTS", func(c *AppSettings, v string) error { return parseScoreWeights(v, &c.Scoring) }},
+	{"GHW_REPO_SEARCH", func(c *AppSettings, v string) error { return setBool(&c.IncludeRepoSearch, v) }},
+	{"GHW_QUERIES_FILE", func(c *AppSettings, v string) error { c.QueriesFile = v; return nil }},
+	{"GHW_SEEN_FILE", func(c *AppSettings, v string) error { c.SeenFile = v; return nil }},
+	{"GHW_NEW_ONLY", func(c *AppSettings, v string) error { return setBool(&c.NewOnly, v) }},
+	{"GHW_SKIP_OPENAI", func(c *AppSettings, v string) error { return setBool(&c.SkipOpenAI, v) }},
+	{"GHW_SCHEDULES", func(c *AppSettings, v string) error { c.Schedules = splitList(v, ";"); return nil }},
+	{"GHW_MAX_PARALLEL_RUNS", func(c *AppSettings, v string) error { return setInt(&c.MaxParallelRuns, v) }},
+	{"GHW_RUN_POLICY", func(c *AppSettings, v string) error { c.RunPolicy = v; return nil }},
+	{"GHW_RUNS_DIR", func(c *AppSettings, v string) error { c.RunsDir = v; return nil }},
+	{"GHW_CACHE_DIR", func(c *AppSettings, v string) error { c.CacheDir = v; return nil }},
+}
+
+// applySettingsEnv applies every GHW_* variable that is set and reports how many were.
+func applySettingsEnv(c *AppSettings) (int, error) {
+	n := 0
+	for _, e := range settingsEnv {
+		v, ok := os.LookupEnv(e.key)
+		if !ok {
+			continue
+		}
+		if err := e.apply(c, strings.TrimSpace(v)); err != nil {
+			return n, fmt.Errorf("%s: %w", e.key, err)
+		}
+		n++
+	}
+	return n, nil
+}
+
+func setInt(dst *int, v string) error {
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return err
+	}
+	*dst = n
+	return nil
+}
+
+func setBool(dst *bool, v string) error {
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return err
+	}
+	*dst = b
+	return nil
+}
+
+func splitList(v, sep string) []string {
+	var out []string
+	for _, p := range strings.Split(v, sep) {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
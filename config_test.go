@@ -0,0 +1,117 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// clearSettingsEnv unsets every variable resolveSettings reads, restoring them after t.
+func clearSettingsEnv(t *testing.T) {
+	t.Helper()
+	keys := []string{"GHW_SETTINGS_FILE"}
+	for _, e := range settingsEnv {
+		keys = append(keys, e.key)
+	}
+	for _, k := range keys {
+		t.Setenv(k, "") // registers the restore
+		os.Unsetenv(k)
+	}
+}
+
+func writeSettings(t *testing.T, path, body string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResolveSettingsPrecedence(t *testing.T) {
+	dir := t.TempDir() // not the working directory
+	queries := filepath.Join(dir, "queries.yaml")
+	writeSettings(t, filepath.Join(dir, defaultSettingsFile), `{"daysBack": 10, "maxPages": 3, "seenFile": "state/seen.json"}`)
+	empty := t.TempDir()
+	other := filepath.Join(t.TempDir(), "custom.json")
+	writeSettings(t, other, `{"daysBack": 12}`)
+
+	tests := []struct {
+		name     string
+		env      map[string]string
+		args     []string
+		days     int
+		maxPages int
+		file     string // settings file resolved
+		set      bool   // file found or GHW_* applied
+	}{
+		{"no file", nil, []string{"-queries", filepath.Join(empty, "queries.yaml")}, defaultDaysBack, maxPagesDefault, filepath.Join(empty, defaultSettingsFile), false},
+		{"file beside -queries", nil, []string{"-queries", queries}, 10, 3, filepath.Join(dir, defaultSettingsFile), true},
+		{"file beside GHW_QUERIES_FILE", map[string]string{"GHW_QUERIES_FILE": queries}, nil, 10, 3, filepath.Join(dir, defaultSettingsFile), true},
+		{"env over file", map[string]string{"GHW_QUERIES_FILE": queries, "GHW_DAYS_BACK": "20"}, nil, 20, 3, filepath.Join(dir, defaultSettingsFile), true},
+		{"flag over env", map[string]string{"GHW_QUERIES_FILE": queries, "GHW_DAYS_BACK": "20"}, []string{"-days", "30"}, 30, 3, filepath.Join(dir, defaultSettingsFile), true},
+		{"-settings", nil, []string{"-settings", other, "-queries", queries}, 12, maxPagesDefault, other, true},
+		{"GHW_SETTINGS_FILE", map[string]string{"GHW_SETTINGS_FILE": other}, nil, 12, maxPagesDefault, other, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearSettingsEnv(t)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			cfg, opts, err := resolveSettings("test", tt.args, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.DaysBack != tt.days || cfg.MaxPages != tt.maxPages {
+				t.Errorf("days=%d maxPages=%d, want %d and %d", cfg.DaysBack, cfg.MaxPages, tt.days, tt.maxPages)
+			}
+			if opts.settingsFile != tt.file {
+				t.Errorf("settings file = %s, want %s", opts.settingsFile, tt.file)
+			}
+			if tt.file == filepath.Join(dir, defaultSettingsFile) && cfg.SeenFile != filepath.Join(dir, "state", "seen.json") {
+				t.Errorf("seenFile = %s, want it relative to %s", cfg.SeenFile, dir)
+			}
+			if opts.settingsSet != tt.set {
+				t.Errorf("settingsSet = %v, want %v", opts.settingsSet, tt.set)
+			}
+		})
+	}
+}
+
+func TestResolveSettingsMissingExplicitFile(t *testing.T) {
+	clearSettingsEnv(t)
+	if _, _, err := resolveSettings("test", []string{"-settings", filepath.Join(t.TempDir(), "nope.json")}, nil); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("err = %v, want not exist", err)
+	}
+}
+
+func TestSettingsFileRelativePaths(t *testing.T) {
+	dir := t.TempDir()
+	outside := t.TempDir()
+	path := filepath.Join(dir, defaultSettingsFile)
+
+	cfg := defaultSettings()
+	cfg.QueriesFile = filepath.Join(dir, "queries.yaml")
+	cfg.SeenFile = filepath.Join(dir, "state", "seen.json")
+	cfg.RunsDir = filepath.Join(outside, "runs")
+	cfg.CacheDir = ""
+	if err := saveSettingsFile(path, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	var raw AppSettings
+	if err := readJSONFile(path, &raw); err != nil {
+		t.Fatal(err)
+	}
+	if raw.QueriesFile != "queries.yaml" || raw.SeenFile != filepath.Join("state", "seen.json") || raw.RunsDir != cfg.RunsDir || raw.CacheDir != "" {
+		t.Errorf("stored paths = %q %q %q %q, want inside ones relative and outside ones absolute", raw.QueriesFile, raw.SeenFile, raw.RunsDir, raw.CacheDir)
+	}
+
+	got, found, err := loadSettingsFile(path)
+	if err != nil || !found {
+		t.Fatalf("load: found=%v err=%v", found, err)
+	}
+	if got.QueriesFile != cfg.QueriesFile || got.SeenFile != cfg.SeenFile || got.RunsDir != cfg.RunsDir {
+		t.Errorf("loaded paths = %q %q %q, want %q %q %q", got.QueriesFile, got.SeenFile, got.RunsDir, cfg.QueriesFile, cfg.SeenFile, cfg.RunsDir)
+	}
+}
@@ -0,0 +1,260 @@
+// diff.go
+// Run-to-run diff: what appeared, disappeared or moved between two runs' Findings.
+// Used by /api/diff and the "Changes since last run" section of each report.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type pushChange struct {
+	FullName string    `json:"fullName"`
+	HTMLURL  string    `json:"htmlUrl"`
+	Before   time.Time `json:"before"`
+	After    time.Time `json:"after"`
+}
+
+// FindingsDiff lists the hits in To that are not in From and vice versa.
+type FindingsDiff struct {
+	From        string       `json:"from"`
+	To          string       `json:"to"`
+	NewRepos    []RepoHit    `json:"newRepos"`
+	NewFiles    []CodeHit    `json:"newFiles"`
+	GoneRepos   []RepoHit    `json:"goneRepos"`
+	GoneFiles   []CodeHit    `json:"goneFiles"`
+	NewCommits  []CommitHit  `json:"newCommits"`
+	GoneCommits []CommitHit  `json:"goneCommits"`
+	NewIssues   []IssueHit   `json:"newIssues"` // issues and pulls
+	GoneIssues  []IssueHit   `json:"goneIssues"`
+	PushChanged []pushChange `json:"pushChanged"` // repo hits in both runs whose pushedAt moved
+}
+
+func (d FindingsDiff) empty() bool {
+	return len(d.NewRepos)+len(d.NewFiles)+len(d.GoneRepos)+len(d.GoneFiles)+
+		len(d.NewCommits)+len(d.GoneCommits)+len(d.NewIssues)+len(d.GoneIssues)+len(d.PushChanged) == 0
+}
+
+// diffKeyed returns the hits of to whose key is not in from, and those of from whose
+// key is not in to.
+func diffKeyed[T any](from, to []T, key func(T) string) (added, gone []T) {
+	added, gone = []T{}, []T{}
+	oldKeys := map[string]bool{}
+	for _, h := range from {
+		oldKeys[key(h)] = true
+	}
+	newKeys := map[string]bool{}
+	for _, h := range to {
+		newKeys[key(h)] = true
+		if !oldKeys[key(h)] {
+			added = append(added, h)
+		}
+	}
+	for _, h := range from {
+		if !newKeys[key(h)] {
+			gone = append(gone, h)
+		}
+	}
+	return added, gone
+}
+
+// diffFindings compares two runs' findings, keyed the same way as the seen store.
+func diffFindings(from, to Findings) FindingsDiff {
+	d := FindingsDiff{From: from.RunID, To: to.RunID, NewRepos: []RepoHit{}, GoneRepos: []RepoHit{}, PushChanged: []pushChange{}}
+	d.NewFiles, d.GoneFiles = diffKeyed(from.CodeHits, to.CodeHits, codeKey)
+	d.NewCommits, d.GoneCommits = diffKeyed(from.CommitHits, to.CommitHits, commitKey)
+	d.NewIssues, d.GoneIssues = diffKeyed(from.IssueHits, to.IssueHits, issueKey)
+
+	oldRepo := map[string]RepoHit{}
+	for _, h := range from.RepoHits {
+		oldRepo[repoKey(h)] = h
+	}
+	newRepo := map[string]bool{}
+	for _, h := range to.RepoHits {
+		newRepo[repoKey(h)] = true
+		prev, ok := oldRepo[repoKey(h)]
+		switch {
+		case !ok:
+			d.NewRepos = append(d.NewRepos, h)
+		case !prev.PushedAt.Equal(h.PushedAt):
+			d.PushChanged = append(d.PushChanged, pushChange{FullName: h.FullName, HTMLURL: h.HTMLURL, Before: prev.PushedAt, After: h.PushedAt})
+		}
+	}
+	for _, h := range from.RepoHits {
+		if !newRepo[repoKey(h)] {
+			d.GoneRepos = append(d.GoneRepos, h)
+		}
+	}
+	sort.Slice(d.PushChanged, func(i, j int) bool { return d.PushChanged[i].After.After(d.PushChanged[j].After) })
+	return d
+}
+
+// previousRun returns the newest completed stored run that started before t, skipping skipID.
+func previousRun(h historyStore, t time.Time, skipID string) (runRecord, error) {
+	recs, err := h.list()
+	if err != nil {
+		return runRecord{}, err
+	}
+	for _, rec := range recs {
+		if rec.ID == skipID || rec.State != runDone || !rec.Started.Before(t) {
+			continue
+		}
+		return h.load(rec.ID)
+	}
+	return runRecord{}, errNoPreviousRun
+}
+
+var errNoPreviousRun = errors.New("no previous completed run")
+
+const maxDiffListed = 20
+
+// diffMarkdown renders d as a "Changes since last run" report section.
+func diffMarkdown(d FindingsDiff) string {
+	var b strings.Builder
+	b.WriteString("## Changes since last run\n\n")
+	fmt.Fprintf(&b, "Compared with run `%s`.\n\n", d.From)
+	if d.empty() {
+		b.WriteString("No changes.\n")
+		return b.String()
+	}
+	fmt.Fprintf(&b, "- New repos: %d\n- New files: %d\n- New commits: %d\n- New issues/pulls: %d\n", len(d.NewRepos), len(d.NewFiles), len(d.NewCommits), len(d.NewIssues))
+	fmt.Fprintf(&b, "- Disappeared repos: %d\n- Disappeared files: %d\n- Disappeared commits: %d\n- Disappeared issues/pulls: %d\n- Push date changed: %d\n\n",
+		len(d.GoneRepos), len(d.GoneFiles), len(d.GoneCommits), len(d.GoneIssues), len(d.PushChanged))
+
+	writeRepos := func(title string, hits []RepoHit) {
+		if len(hits) == 0 {
+			return
+		}
+		b.WriteString("### " + title + "\n\n")
+		for i, h := range hits {
+			if i == maxDiffListed {
+				b.WriteString("- … and " + strconv.Itoa(len(hits)-i) + " more\n")
+				break
+			}
+			fmt.Fprintf(&b, "- [%s](%s)\n", h.FullName, h.HTMLURL)
+		}
+		b.WriteString("\n")
+	}
+	writeFiles := func(title string, hits []CodeHit) {
+		if len(hits) == 0 {
+			return
+		}
+		b.WriteString("### " + title + "\n\n")
+		for i, h := range hits {
+			if i == maxDiffListed {
+				b.WriteString("- … and " + strconv.Itoa(len(hits)-i) + " more\n")
+				break
+			}
+			fmt.Fprintf(&b, "- %s: [%s](%s)\n", h.Repository, h.FilePath, h.FileURL)
+		}
+		b.WriteString("\n")
+	}
+	writeCommits := func(title string, hits []CommitHit) {
+		if len(hits) == 0 {
+			return
+		}
+		b.WriteString("### " + title + "\n\n")
+		for i, h := range hits {
+			if i == maxDiffListed {
+				b.WriteString("- … and " + strconv.Itoa(len(hits)-i) + " more\n")
+				break
+			}
+			fmt.Fprintf(&b, "- %s: [%s](%s)\n", h.Repository, h.Message, h.CommitURL)
+		}
+		b.WriteString("\n")
+	}
+	writeIssues := func(title string, hits []IssueHit) {
+		if len(hits) == 0 {
+			return
+		}
+		b.WriteString("### " + title + "\n\n")
+		for i, h := range hits {
+			if i == maxDiffListed {
+				b.WriteString("- … and " + strconv.Itoa(len(hits)-i) + " more\n")
+				break
+			}
+			fmt.Fprintf(&b, "- %s #%d (%s): [%s](%s)\n", h.Repository, h.Number, h.Kind, h.Title, h.HTMLURL)
+		}
+		b.WriteString("\n")
+	}
+	writeRepos("New repos", d.NewRepos)
+	writeFiles("New files", d.NewFiles)
+	writeCommits("New commits", d.NewCommits)
+	writeIssues("New issues and pulls", d.NewIssues)
+	writeRepos("Disappeared repos", d.GoneRepos)
+	writeFiles("Disappeared files", d.GoneFiles)
+	writeCommits("Disappeared commits", d.GoneCommits)
+	writeIssues("Disappeared issues and pulls", d.GoneIssues)
+	if len(d.PushChanged) > 0 {
+		b.WriteString("### Push date changed\n\n")
+		for i, c := range d.PushChanged {
+			if i == maxDiffListed {
+				b.WriteString("- … and " + strconv.Itoa(len(d.PushChanged)-i) + " more\n")
+				break
+			}
+			fmt.Fprintf(&b, "- [%s](%s): %s → %s\n", c.FullName, c.HTMLURL, c.Before.Format("2006-01-02"), c.After.Format("2006-01-02"))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// changesSection diffs f against the newest completed run in runsDir before it.
+// It returns "" when there is nothing to compare against.
+func changesSection(runsDir string, f Findings, started time.Time) (string, error) {
+	prev, err := previousRun(historyStore{dir: runsDir}, started, f.RunID)
+	if errors.Is(err, errNoPreviousRun) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return diffMarkdown(diffFindings(prev.Findings, f)), nil
+}
+
+// ====== HTTP ======
+
+// handleDiff compares two runs: ?to= defaults to the latest run, ?from= to the
+// completed run before it. ?format=md returns the Markdown section instead of JSON.
+func (s *Server) handleDiff(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	to, ok := s.findRun(q.Get("to"))
+	if !ok {
+		http.Error(w, "unknown run: "+q.Get("to"), 404)
+		return
+	}
+	var from runResult
+	if id := q.Get("from"); id != "" {
+		if from, ok = s.findRun(id); !ok {
+			http.Error(w, "unknown run: "+id, 404)
+			return
+		}
+	} else {
+		rec, err := previousRun(s.history(), to.Started, to.ID)
+		if err != nil {
+			http.Error(w, err.Error(), 404)
+			return
+		}
+		from = rec.runResult
+	}
+	for _, res := range []runResult{from, to} {
+		if res.State == runRunning || res.State == runQueued {
+			http.Error(w, "run still in progress: "+res.ID, 409)
+			return
+		}
+	}
+	from.Findings.RunID, to.Findings.RunID = from.ID, to.ID
+	d := diffFindings(from.Findings, to.Findings)
+	if q.Get("format") == "md" {
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		_, _ = w.Write([]byte(diffMarkdown(d)))
+		return
+	}
+	writeJSON(w, d)
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDiffFindings(t *testing.T) {
+	day := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	code := func(repo, path string) CodeHit {
+		return CodeHit{Repository: repo, FilePath: path, FileURL: "https://github.com/" + repo + "/blob/main/" + path}
+	}
+	repo := func(name string, pushed time.Time) RepoHit { return RepoHit{FullName: name, PushedAt: pushed} }
+	commit := func(repo, sha string) CommitHit { return CommitHit{Repository: repo, SHA: sha} }
+	issue := func(url string) IssueHit { return IssueHit{HTMLURL: url} }
+
+	// keys lists each FindingsDiff bucket by hit key; PushChanged by repo name
+	type keys struct {
+		newFiles, goneFiles, newRepos, goneRepos, newCommits, goneCommits, newIssues, goneIssues, pushChanged []string
+	}
+	tests := []struct {
+		name     string
+		from, to Findings
+		want     keys
+	}{
+		{
+			name: "identical",
+			from: Findings{CodeHits: []CodeHit{code("a/b", "x.py")}, RepoHits: []RepoHit{repo("a/b", day)}, CommitHits: []CommitHit{commit("a/b", "1")}, IssueHits: []IssueHit{issue("u/1")}},
+			to:   Findings{CodeHits: []CodeHit{code("a/b", "x.py")}, RepoHits: []RepoHit{repo("a/b", day)}, CommitHits: []CommitHit{commit("a/b", "1")}, IssueHits: []IssueHit{issue("u/1")}},
+		},
+		{
+			name: "new",
+			from: Findings{},
+			to:   Findings{CodeHits: []CodeHit{code("a/b", "x.py")}, RepoHits: []RepoHit{repo("a/b", day)}, CommitHits: []CommitHit{commit("a/b", "1")}, IssueHits: []IssueHit{issue("u/1")}},
+			want: keys{newFiles: []string{"a/b|x.py|https://github.com/a/b/blob/main/x.py"}, newRepos: []string{"a/b"}, newCommits: []string{"a/b@1"}, newIssues: []string{"u/1"}},
+		},
+		{
+			name: "gone",
+			from: Findings{CodeHits: []CodeHit{code("a/b", "x.py")}, RepoHits: []RepoHit{repo("a/b", day)}, CommitHits: []CommitHit{commit("a/b", "1")}, IssueHits: []IssueHit{issue("u/1")}},
+			to:   Findings{},
+			want: keys{goneFiles: []string{"a/b|x.py|https://github.com/a/b/blob/main/x.py"}, goneRepos: []string{"a/b"}, goneCommits: []string{"a/b@1"}, goneIssues: []string{"u/1"}},
+		},
+		{
+			name: "mixed",
+			from: Findings{
+				CodeHits:   []CodeHit{code("a/b", "x.py"), code("a/b", "y.py")},
+				RepoHits:   []RepoHit{repo("a/b", day), repo("c/d", day), repo("e/f", day)},
+				CommitHits: []CommitHit{commit("a/b", "1"), commit("a/b", "2")},
+				IssueHits:  []IssueHit{issue("u/1"), issue("u/2")},
+			},
+			to: Findings{
+				CodeHits:   []CodeHit{code("a/b", "y.py"), code("c/d", "x.py")},
+				RepoHits:   []RepoHit{repo("a/b", day), repo("c/d", day.AddDate(0, 0, 2)), repo("g/h", day)},
+				CommitHits: []CommitHit{commit("a/b", "2"), commit("c/d", "1")},
+				IssueHits:  []IssueHit{issue("u/2"), issue("u/3")},
+			},
+			want: keys{
+				newFiles: []string{"c/d|x.py|https://github.com/c/d/blob/main/x.py"}, goneFiles: []string{"a/b|x.py|https://github.com/a/b/blob/main/x.py"},
+				newRepos: []string{"g/h"}, goneRepos: []string{"e/f"}, pushChanged: []string{"c/d"},
+				newCommits: []string{"c/d@1"}, goneCommits: []string{"a/b@1"},
+				newIssues: []string{"u/3"}, goneIssues: []string{"u/1"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		d := diffFindings(tt.from, tt.to)
+		var got keys
+		for _, h := range d.NewFiles {
+			got.newFiles = append(got.newFiles, codeKey(h))
+		}
+		for _, h := range d.GoneFiles {
+			got.goneFiles = append(got.goneFiles, codeKey(h))
+		}
+		for _, h := range d.NewRepos {
+			got.newRepos = append(got.newRepos, repoKey(h))
+		}
+		for _, h := range d.GoneRepos {
+			got.goneRepos = append(got.goneRepos, repoKey(h))
+		}
+		for _, h := range d.NewCommits {
+			got.newCommits = append(got.newCommits, commitKey(h))
+		}
+		for _, h := range d.GoneCommits {
+			got.goneCommits = append(got.goneCommits, commitKey(h))
+		}
+		for _, h := range d.NewIssues {
+			got.newIssues = append(got.newIssues, issueKey(h))
+		}
+		for _, h := range d.GoneIssues {
+			got.goneIssues = append(got.goneIssues, issueKey(h))
+		}
+		for _, c := range d.PushChanged {
+			got.pushChanged = append(got.pushChanged, c.FullName)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: diff = %+v, want %+v", tt.name, got, tt.want)
+		}
+		if d.empty() != reflect.DeepEqual(tt.want, keys{}) {
+			t.Errorf("%s: empty() = %v", tt.name, d.empty())
+		}
+	}
+}
+
+func TestDiffFindingsPushChange(t *testing.T) {
+	before := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	after := before.AddDate(0, 0, 3)
+	d := diffFindings(Findings{RepoHits: []RepoHit{{FullName: "a/b", PushedAt: before}}}, Findings{RepoHits: []RepoHit{{FullName: "a/b", PushedAt: after}}})
+	if len(d.PushChanged) != 1 || !d.PushChanged[0].Before.Equal(before) || !d.PushChanged[0].After.Equal(after) {
+		t.Errorf("PushChanged = %+v, want a/b moved from %s to %s", d.PushChanged, before, after)
+	}
+}
@@ -0,0 +1,224 @@
+// exclude.go
+// Exclusion rules from queries.yaml: a top-level `exclude:` for every group plus one per
+// group, applied to merged hits after search. Owner and repo globs apply to every kind
+// of hit, path regexes to code hits, and minStars/archived/forks to hits whose
+// repository metadata is known (code hits once enriched, repo hits from search).
+// A group's rules only withdraw that group's matches: a hit another group also found
+// stays, credited to the remaining groups.
+
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+type ExcludeRules struct {
+	Owners   []string `yaml:"owners"`   // globs on the owner, e.g. polygon-io, alpaca*
+	Repos    []string `yaml:"repos"`    // globs on owner/name, e.g. polygon-io/*, */awesome-*
+	Paths    []string `yaml:"paths"`    // regexes on the file path, e.g. (^|/)node_modules/
+	MinStars int      `yaml:"minStars"` // drop repositories with fewer stars
+	Archived bool     `yaml:"archived"` // drop archived repositories
+	Forks    bool     `yaml:"forks"`    // drop forks
+}
+
+// repoFacts is what the rules see of a hit's repository; meta is nil when unknown.
+type repoFacts struct {
+	repo string // owner/name
+	path string // file path, code hits only
+	meta *RepoMeta
+}
+
+// exclusions holds the compiled rules and counts what they dropped.
+type exclusions struct {
+	global  compiledRules
+	byGroup map[string]compiledRules
+	counts  map[string]map[string]int // kind → reason → hits
+}
+
+type compiledRules struct {
+	ExcludeRules
+	paths []*regexp.Regexp
+}
+
+func compileRules(r ExcludeRules) (compiledRules, error) {
+	c := compiledRules{ExcludeRules: r}
+	for _, g := range append(append([]string(nil), r.Owners...), r.Repos...) {
+		if _, err := path.Match(g, ""); err != nil {
+			return c, fmt.Errorf("bad glob %q: %w", g, err)
+		}
+	}
+	for _, p := range r.Paths {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return c, fmt.Errorf("bad path regex %q: %w", p, err)
+		}
+		c.paths = append(c.paths, re)
+	}
+	return c, nil
+}
+
+func newExclusions(spec *QueriesSpec) (*exclusions, error) {
+	global, err := compileRules(spec.Exclude)
+	if err != nil {
+		return nil, fmt.Errorf("exclude: %w", err)
+	}
+	ex := &exclusions{global: global, byGroup: map[string]compiledRules{}, counts: map[string]map[string]int{}}
+	for _, g := range spec.Groups {
+		c, err := compileRules(g.Exclude)
+		if err != nil {
+			return nil, fmt.Errorf("%s: exclude: %w", g.Name, err)
+		}
+		ex.byGroup[g.Name] = c
+	}
+	return ex, nil
+}
+
+// reason says which rule drops a repository, or "".
+func (c compiledRules) reason(f repoFacts) string {
+	owner, _, _ := strings.Cut(f.repo, "/")
+	switch {
+	case globMatch(c.Owners, owner):
+		return "owner"
+	case globMatch(c.Repos, f.repo):
+		return "repo"
+	}
+	for _, re := range c.paths {
+		if f.path != "" && re.MatchString(f.path) {
+			return "path"
+		}
+	}
+	if m := f.meta; m != nil {
+		switch {
+		case c.MinStars > 0 && m.Stars < c.MinStars:
+			return "stars"
+		case c.Archived && m.Archived:
+			return "archived"
+		case c.Forks && m.Fork:
+			return "fork"
+		}
+	}
+	return ""
+}
+
+func globMatch(globs []string, s string) bool {
+	s = strings.ToLower(s)
+	for _, g := range globs {
+		if ok, _ := path.Match(strings.ToLower(g), s); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// drop reports whether a merged hit of kind is excluded, counting it if so. Otherwise
+// it removes from refs the matches of groups whose own rules exclude the hit and
+// returns the first remaining one, for the hit's group and query name.
+func (ex *exclusions) drop(kind string, refs *[]QueryRef, f repoFacts) (QueryRef, bool) {
+	r := ex.global.reason(f)
+	if r == "" {
+		kept := make([]QueryRef, 0, len(*refs))
+		for _, ref := range *refs {
+			if gr := ex.byGroup[ref.Group].reason(f); gr == "" {
+				kept = append(kept, ref)
+			} else if r == "" {
+				r = gr
+			}
+		}
+		if len(kept) > 0 {
+			*refs = kept
+			return kept[0], false
+		}
+	}
+	if ex.counts[kind] == nil {
+		ex.counts[kind] = map[string]int{}
+	}
+	ex.counts[kind][r]++
+	return QueryRef{}, true
+}
+
+// needsMeta reports whether any rule can only be checked with repository metadata.
+func (ex *exclusions) needsMeta() bool {
+	for _, c := range append([]compiledRules{ex.global}, mapValues(ex.byGroup)...) {
+		if c.MinStars > 0 || c.Archived || c.Forks {
+			return true
+		}
+	}
+	return false
+}
+
+func mapValues(m map[string]compiledRules) []compiledRules {
+	out := make([]compiledRules, 0, len(m))
+	for _, v := range m {
+		out = append(out, v)
+	}
+	return out
+}
+
+// code, repos, commits and issues filter hits already merged by dedupeCode etc., so
+// each is counted once however many searches found it.
+func (ex *exclusions) code(hits []CodeHit) []CodeHit {
+	out := hits[:0]
+	for _, h := range hits {
+		if ref, drop := ex.drop("code", &h.MatchedBy, repoFacts{repo: h.Repository, path: h.FilePath, meta: h.RepoMeta}); !drop {
+			h.Group, h.QueryName = ref.Group, ref.Query
+			out = append(out, h)
+		}
+	}
+	return out
+}
+
+func (ex *exclusions) repos(hits []RepoHit) []RepoHit {
+	out := hits[:0]
+	for _, h := range hits {
+		meta := &RepoMeta{Stars: h.Stars, Archived: h.Archived, Fork: h.Fork}
+		if ref, drop := ex.drop("repo", &h.MatchedBy, repoFacts{repo: h.FullName, meta: meta}); !drop {
+			h.Group, h.QueryName = ref.Group, ref.Query
+			out = append(out, h)
+		}
+	}
+	return out
+}
+
+func (ex *exclusions) commits(hits []CommitHit) []CommitHit {
+	out := hits[:0]
+	for _, h := range hits {
+		if ref, drop := ex.drop("commit", &h.MatchedBy, repoFacts{repo: h.Repository}); !drop {
+			h.Group, h.QueryName = ref.Group, ref.Query
+			out = append(out, h)
+		}
+	}
+	return out
+}
+
+func (ex *exclusions) issues(hits []IssueHit) []IssueHit {
+	out := hits[:0]
+	for _, h := range hits {
+		if ref, drop := ex.drop("issue", &h.MatchedBy, repoFacts{repo: h.Repository}); !drop {
+			h.Group, h.QueryName = ref.Group, ref.Query
+			out = append(out, h)
+		}
+	}
+	return out
+}
+
+// notes renders the counts, e.g. "Excluded 12 code hits (owner 9, path 3)".
+func (ex *exclusions) notes() []string {
+	var out []string
+	for _, kind := range []string{"code", "repo", "commit", "issue"} {
+		byReason := ex.counts[kind]
+		if len(byReason) == 0 {
+			continue
+		}
+		total := 0
+		var parts []string
+		for _, r := range sortedKeys(byReason) {
+			total += byReason[r]
+			parts = append(parts, fmt.Sprintf("%s %d", r, byReason[r]))
+		}
+		out = append(out, fmt.Sprintf("Excluded %d %s hits (%s)", total, kind, strings.Join(parts, ", ")))
+	}
+	return out
+}
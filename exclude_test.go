@@ -0,0 +1,129 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExcludeRulesReason(t *testing.T) {
+	rules := ExcludeRules{
+		Owners:   []string{"polygon-io", "alpaca*"},
+		Repos:    []string{"*/awesome-*"},
+		Paths:    []string{`(^|/)node_modules/`},
+		MinStars: 5,
+		Archived: true,
+		Forks:    true,
+	}
+	c, err := compileRules(rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		f    repoFacts
+		want string
+	}{
+		{"owner", repoFacts{repo: "polygon-io/client-python"}, "owner"},
+		{"owner glob, any case", repoFacts{repo: "AlpacaHQ/alpaca-py"}, "owner"},
+		{"repo glob", repoFacts{repo: "someone/awesome-trading"}, "repo"},
+		{"path", repoFacts{repo: "a/b", path: "web/node_modules/x/index.js"}, "path"},
+		{"path needs a path", repoFacts{repo: "a/node_modules"}, ""},
+		{"stars", repoFacts{repo: "a/b", meta: &RepoMeta{Stars: 4}}, "stars"},
+		{"archived", repoFacts{repo: "a/b", meta: &RepoMeta{Stars: 50, Archived: true}}, "archived"},
+		{"fork", repoFacts{repo: "a/b", meta: &RepoMeta{Stars: 50, Fork: true}}, "fork"},
+		{"metadata rules wait for metadata", repoFacts{repo: "a/b"}, ""},
+		{"kept", repoFacts{repo: "a/b", path: "src/main.py", meta: &RepoMeta{Stars: 50}}, ""},
+	}
+	for _, tt := range tests {
+		if got := c.reason(tt.f); got != tt.want {
+			t.Errorf("%s: reason = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCompileRulesErrors(t *testing.T) {
+	for _, r := range []ExcludeRules{
+		{Owners: []string{"[a-"}},
+		{Repos: []string{"a/[b"}},
+		{Paths: []string{"(unclosed"}},
+	} {
+		if _, err := compileRules(r); err == nil {
+			t.Errorf("compileRules(%+v): want error", r)
+		}
+	}
+}
+
+func TestExclusionsOnMergedHits(t *testing.T) {
+	spec := &QueriesSpec{
+		Exclude: ExcludeRules{Owners: []string{"polygon-io"}},
+		Groups: []SearchGroup{
+			{Name: "A", Exclude: ExcludeRules{Repos: []string{"x/mirror"}}},
+			{Name: "B"},
+		},
+	}
+	hit := func(repo, group, query string) CodeHit {
+		return CodeHit{Group: group, QueryName: query, Repository: repo, FilePath: "main.py", FileURL: "https://github.com/" + repo + "/main.py"}
+	}
+	raw := []CodeHit{
+		hit("polygon-io/sdk", "A", "q1"),
+		hit("polygon-io/sdk", "B", "q2"), // same file: one merged hit, one exclusion
+		hit("x/mirror", "A", "q1"),
+		hit("x/mirror", "B", "q2"), // B still wants it
+		hit("x/mirror2", "A", "q1"),
+		hit("y/lib", "A", "q1"),
+	}
+	tests := []struct {
+		name   string
+		hits   []CodeHit
+		repos  []string
+		groups []string // Group of each kept hit
+		note   string
+	}{
+		{"merged then excluded", raw, []string{"x/mirror", "x/mirror2", "y/lib"}, []string{"B", "A", "A"}, "Excluded 1 code hits (owner 1)"},
+		{"group rule alone", raw[2:3], []string{}, []string{}, "Excluded 1 code hits (repo 1)"},
+	}
+	for _, tt := range tests {
+		ex, err := newExclusions(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hits := append([]CodeHit(nil), tt.hits...)
+		merged := ex.code(dedupeCode(hits))
+		// running again, as partial does, changes nothing
+		merged = ex.code(dedupeCode(merged))
+		repos, groups := []string{}, []string{}
+		for _, h := range merged {
+			repos, groups = append(repos, h.Repository), append(groups, h.Group)
+			for _, r := range h.MatchedBy {
+				if r.Group == "A" && h.Repository == "x/mirror" {
+					t.Errorf("%s: x/mirror still credited to group A: %v", tt.name, h.MatchedBy)
+				}
+			}
+		}
+		if !reflect.DeepEqual(repos, tt.repos) || !reflect.DeepEqual(groups, tt.groups) {
+			t.Errorf("%s: kept %v in groups %v, want %v in %v", tt.name, repos, groups, tt.repos, tt.groups)
+		}
+		if notes := ex.notes(); len(notes) != 1 || notes[0] != tt.note {
+			t.Errorf("%s: notes = %q, want %q", tt.name, notes, tt.note)
+		}
+	}
+}
+
+func TestLoadQueriesDuplicateGroup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queries.yaml")
+	yml := `groups:
+  - name: Alpaca
+    searches: [{name: q, type: code, query: alpaca}]
+  - name: Alpaca
+    searches: [{name: q, type: code, query: alpaca-py}]
+`
+	if err := os.WriteFile(path, []byte(yml), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadQueries(path); err == nil || !strings.Contains(err.Error(), "duplicate group name") {
+		t.Errorf("loadQueries error = %v, want duplicate group name", err)
+	}
+}
@@ -0,0 +1,345 @@
+// github.go
+// GitHub REST client. Every API URL is built here from the client's base, so a query
+// group can point at GitHub Enterprise Server (apiBase + tokenEnv in queries.yaml)
+// while the rest use api.github.com. Credentials come from tokenSources (auth.go); with
+// several, each request goes to the one with the most rate-limit budget left.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Media types for Accept: plain JSON, or JSON with text_matches on search results.
+const (
+	mediaJSON      = "application/vnd.github+json"
+	mediaTextMatch = "application/vnd.github.text-match+json"
+)
+
+const (
+	defaultAPIBase   = "https://api.github.com"
+	defaultTokenEnv  = "GITHUB_TOKEN"
+	ghRequestTimeout = 30 * time.Second // per request, not counting rate-limit waits
+)
+
+type ghClient struct {
+	apiBase string // e.g. https://api.github.com or https://ghe.example.com/api/v3
+	creds   []credential
+	retries *retryBudget
+	cache   *httpCache
+	usage   *tokenUsage
+	budget  *runBudget // the run's search-phase deadline; nil outside runReport
+	emit    func(DebugEvent)
+}
+
+// credential is one token and the limiter tracking its budget.
+type credential struct {
+	auth    tokenSource
+	limiter *rateLimiter
+}
+
+func credentialsFor(apiBase string, srcs []tokenSource) []credential {
+	out := make([]credential, len(srcs))
+	for i, s := range srcs {
+		out[i] = credential{auth: s, limiter: limiterFor(apiBase, s.name())}
+	}
+	return out
+}
+
+// newGH returns a github.com client for one run; its retry budget, cache counters and
+// token usage are the run's and are shared with the clients forGroup derives from it.
+func newGH(cfg AppSettings, emit func(DebugEvent)) (*ghClient, error) {
+	srcs, err := defaultTokenSources()
+	if err != nil {
+		return nil, err
+	}
+	return &ghClient{apiBase: defaultAPIBase, creds: credentialsFor(defaultAPIBase, srcs),
+		retries: newRetryBudget(retryBudgetRun), cache: newHTTPCache(cfg.CacheDir), usage: newTokenUsage(), emit: emit}, nil
+}
+
+// forGroup returns the client for g's host and token; c itself when g uses the defaults.
+// Groups with their own host or tokenEnv always use that single static token.
+func (c *ghClient) forGroup(g SearchGroup) *ghClient {
+	base, tokenEnv := groupAPIBase(g), groupTokenEnv(g)
+	if base == c.apiBase && tokenEnv == defaultTokenEnv {
+		return c
+	}
+	gc := *c
+	gc.apiBase, gc.creds = base, credentialsFor(base, []tokenSource{envToken(tokenEnv)})
+	return &gc
+}
+
+// pick returns the credential to spend on resource: the one that can send soonest,
+// then the one with the most remaining budget.
+func (c *ghClient) pick(resource string) credential {
+	best := c.creds[0]
+	if len(c.creds) == 1 {
+		return best
+	}
+	bestWait, bestLeft := best.limiter.peek(resource)
+	for _, cr := range c.creds[1:] {
+		wait, left := cr.limiter.peek(resource)
+		if wait < bestWait || (wait == bestWait && left > bestLeft) {
+			best, bestWait, bestLeft = cr, wait, left
+		}
+	}
+	return best
+}
+
+// tokenUsage counts a run's requests per credential and resource for the debug log.
+type tokenUsage struct {
+	mu     sync.Mutex
+	counts map[string]map[string]int
+}
+
+func newTokenUsage() *tokenUsage { return &tokenUsage{counts: map[string]map[string]int{}} }
+
+func (u *tokenUsage) add(cred, resource string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.counts[cred] == nil {
+		u.counts[cred] = map[string]int{}
+	}
+	u.counts[cred][resource]++
+}
+
+// String renders e.g. "GITHUB_TOKENS#1 core=40 search=12; GITHUB_TOKENS#2 search=11".
+func (u *tokenUsage) String() string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	var parts []string
+	for cred, byRes := range u.counts {
+		s := cred
+		for _, res := range sortedKeys(byRes) {
+			s += fmt.Sprintf(" %s=%d", res, byRes[res])
+		}
+		parts = append(parts, s)
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, "; ")
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func groupAPIBase(g SearchGroup) string {
+	if b := strings.TrimRight(strings.TrimSpace(g.APIBase), "/"); b != "" {
+		return b
+	}
+	return defaultAPIBase
+}
+
+func groupTokenEnv(g SearchGroup) string {
+	if e := strings.TrimSpace(g.TokenEnv); e != "" {
+		return e
+	}
+	return defaultTokenEnv
+}
+
+// limiters holds one rate limiter per (API base, credential name): each pair has its own budget.
+var (
+	limitersMu sync.Mutex
+	limiters   = map[[2]string]*rateLimiter{}
+)
+
+func limiterFor(apiBase, credential string) *rateLimiter {
+	limitersMu.Lock()
+	defer limitersMu.Unlock()
+	key := [2]string{apiBase, credential}
+	l, ok := limiters[key]
+	if !ok {
+		l = newRateLimiter()
+		limiters[key] = l
+	}
+	return l
+}
+
+// rateBudgets reports every limiter's buckets for /api/status.
+func rateBudgets() []rateBudget {
+	limitersMu.Lock()
+	defer limitersMu.Unlock()
+	var out []rateBudget
+	for key, l := range limiters {
+		for _, b := range l.snapshot() {
+			b.APIBase, b.Credential = key[0], key[1]
+			out = append(out, b)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].APIBase != out[j].APIBase {
+			return out[i].APIBase < out[j].APIBase
+		}
+		return out[i].Credential < out[j].Credential
+	})
+	return out
+}
+
+// searchURL builds a search request; escapedQuery is already query-escaped.
+func (c *ghClient) searchURL(endpoint, escapedQuery, sortBy string, perPage, page int) string {
+	return fmt.Sprintf("%s/search/%s?q=%s&sort=%s&order=desc&per_page=%d&page=%d",
+		c.apiBase, endpoint, escapedQuery, sortBy, perPage, page)
+}
+
+// repoURL is the repository resource for owner/name.
+func (c *ghClient) repoURL(repo string) string {
+	return c.apiBase + "/repos/" + repo
+}
+
+// commitsURL lists the commits touching path in repo, newest first. It has no since=
+// so the URL, and with it the cache entry, stays the same from run to run; callers
+// compare the commit date with the window themselves.
+func (c *ghClient) commitsURL(repo, path string, perPage int) string {
+	return fmt.Sprintf("%s/repos/%s/commits?path=%s&per_page=%d", c.apiBase, repo, neturl.PathEscape(path), perPage)
+}
+
+// graphqlURL is the GraphQL endpoint: /graphql on api.github.com, /api/graphql on GHES.
+func (c *ghClient) graphqlURL() string {
+	if base, ok := strings.CutSuffix(c.apiBase, "/api/v3"); ok {
+		return base + "/api/graphql"
+	}
+	return c.apiBase + "/graphql"
+}
+
+// webURL is the browser URL for path on the client's host (github.com for api.github.com,
+// the GHES host itself for <host>/api/v3).
+func (c *ghClient) webURL(path string) string {
+	base := strings.TrimSuffix(c.apiBase, "/api/v3")
+	if base == defaultAPIBase {
+		base = "https://github.com"
+	}
+	return base + "/" + strings.TrimPrefix(path, "/")
+}
+
+// get fetches url, retrying transient failures (see retryable) while the run's
+// retry budget lasts. The returned body is fully read.
+func (c *ghClient) get(ctx context.Context, url string) (*http.Response, error) {
+	return c.send(ctx, "GET", url, nil, mediaJSON)
+}
+
+// getTextMatch is get for search results with text_matches (matched fragments).
+func (c *ghClient) getTextMatch(ctx context.Context, url string) (*http.Response, error) {
+	return c.send(ctx, "GET", url, nil, mediaTextMatch)
+}
+
+// post sends a JSON body to url under the same retry policy as get; it is never cached.
+func (c *ghClient) post(ctx context.Context, url string, body []byte) (*http.Response, error) {
+	return c.send(ctx, "POST", url, body, mediaJSON)
+}
+
+func (c *ghClient) send(ctx context.Context, method, url string, body []byte, accept string) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := c.do(ctx, method, url, body, accept)
+		if !retryable(ctx, resp, err) {
+			return resp, err
+		}
+		if attempt >= maxAttempts {
+			return resp, gaveUp(err)
+		}
+		if !c.retries.take() {
+			c.emit(DebugEvent{Phase: "retry-exhausted", URL: url, Note: "run retry budget used up"})
+			return resp, gaveUp(err)
+		}
+		wait := backoff(attempt)
+		ev := DebugEvent{Phase: "retry", URL: url, Note: retryNote(attempt, wait, resp, err)}
+		if resp != nil {
+			ev.Status = resp.StatusCode
+			ev.RateRemaining, ev.RateReset = resp.Header.Get("X-RateLimit-Remaining"), resp.Header.Get("X-RateLimit-Reset")
+		}
+		c.emit(ev)
+		if err := sleepCtx(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// gaveUp marks a transport error as errGaveUp; nil (a retryable status) stays nil.
+func gaveUp(err error) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("%w: %w", errGaveUp, err)
+}
+
+// do makes one attempt: it waits for rate-limit budget, then sends the request. The
+// returned body is fully read, so callers need not worry about the request timeout.
+func (c *ghClient) do(ctx context.Context, method, url string, payload []byte, accept string) (*http.Response, error) {
+	resource := resourceFor(url)
+	cred := c.pick(resource)
+	if err := cred.limiter.wait(ctx, resource, cred.auth.name(), c.emit); err != nil {
+		return nil, err
+	}
+	c.usage.add(cred.auth.name(), resource)
+	reqCtx, cancel := context.WithTimeout(ctx, ghRequestTimeout)
+	defer cancel()
+	var reqBody io.Reader
+	if payload != nil {
+		reqBody = bytes.NewReader(payload)
+	}
+	req, _ := http.NewRequestWithContext(reqCtx, method, url, reqBody)
+	req.Header.Set("Accept", accept)
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	token, err := cred.auth.token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	// the body depends on the media type, so non-default ones get their own entries
+	cacheKey := url
+	if accept != mediaJSON {
+		cacheKey = accept + " " + url
+	}
+	var cached *cacheEntry
+	if method == "GET" {
+		cached = c.cache.lookup(cacheKey)
+	}
+	if cached != nil {
+		cached.condition(req)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	cred.limiter.update(resource, resp)
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		// GitHub doesn't count a 304, so neither does the limiter
+		cred.limiter.refund(resource)
+		c.cache.hits.Add(1)
+		c.cache.touch(cacheKey)
+		return cached.revalidated(resp), nil
+	}
+	if method != "GET" {
+		return resp, nil
+	}
+	c.cache.misses.Add(1)
+	if resp.StatusCode == 200 {
+		if err := c.cache.store(cacheKey, resp, body); err != nil {
+			c.emit(DebugEvent{Phase: "cache-error", URL: url, Note: err.Error()})
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,225 @@
+// history.go
+// On-disk run history: one directory per run under AppSettings.RunsDir holding the
+// run metadata + settings snapshot, findings, report Markdown and debug log.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+)
+
+const defaultRunsDir = "runs"
+
+const (
+	historyMetaFile     = "run.json"
+	historyFindingsFile = "findings.json"
+	historyReportFile   = "report.md"
+	historyDebugFile    = "debug.json"
+)
+
+// runRecord is what run.json holds: the run's outcome plus the settings it ran with.
+type runRecord struct {
+	runResult
+	Settings AppSettings `json:"settings"`
+}
+
+type historyStore struct {
+	dir string
+}
+
+var validRunID = regexp.MustCompile(`^[0-9A-Za-z-]+$`)
+
+func (h historyStore) runDir(id string) (string, error) {
+	if !validRunID.MatchString(id) {
+		return "", fmt.Errorf("invalid run id %q", id)
+	}
+	return filepath.Join(h.dir, id), nil
+}
+
+// save writes a finished run. Files are written individually so a partial write
+// never corrupts other runs.
+func (h historyStore) save(res runResult, cfg AppSettings, events []DebugEvent) error {
+	dir, err := h.runDir(res.ID)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := writeJSONFile(filepath.Join(dir, historyFindingsFile), res.Findings); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, historyReportFile), []byte(res.Markdown), 0644); err != nil {
+		return err
+	}
+	if err := writeJSONFile(filepath.Join(dir, historyDebugFile), events); err != nil {
+		return err
+	}
+	// metadata last: a directory without run.json is an incomplete run and is not listed
+	return writeJSONFile(filepath.Join(dir, historyMetaFile), runRecord{runResult: res, Settings: cfg})
+}
+
+// list returns every stored run, newest first.
+func (h historyStore) list() ([]runRecord, error) {
+	entries, err := os.ReadDir(h.dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var out []runRecord
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		rec, err := h.meta(e.Name())
+		if err != nil {
+			continue
+		}
+		out = append(out, rec)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Started.After(out[j].Started) })
+	return out, nil
+}
+
+func (h historyStore) meta(id string) (runRecord, error) {
+	var rec runRecord
+	dir, err := h.runDir(id)
+	if err != nil {
+		return rec, err
+	}
+	err = readJSONFile(filepath.Join(dir, historyMetaFile), &rec)
+	return rec, err
+}
+
+// load returns a stored run with its Findings and Markdown filled in.
+func (h historyStore) load(id string) (runRecord, error) {
+	rec, err := h.meta(id)
+	if err != nil {
+		return rec, err
+	}
+	dir, _ := h.runDir(id)
+	if err := readJSONFile(filepath.Join(dir, historyFindingsFile), &rec.Findings); err != nil {
+		return rec, err
+	}
+	md, err := os.ReadFile(filepath.Join(dir, historyReportFile))
+	if err != nil {
+		return rec, err
+	}
+	rec.Markdown = string(md)
+	return rec, nil
+}
+
+func (h historyStore) events(id string) ([]DebugEvent, error) {
+	dir, err := h.runDir(id)
+	if err != nil {
+		return nil, err
+	}
+	var evs []DebugEvent
+	err = readJSONFile(filepath.Join(dir, historyDebugFile), &evs)
+	return evs, err
+}
+
+func (h historyStore) delete(id string) error {
+	dir, err := h.runDir(id)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(filepath.Join(dir, historyMetaFile)); err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
+
+func writeJSONFile(path string, v any) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0644)
+}
+
+func readJSONFile(path string, v any) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// ====== HTTP ======
+
+func (s *Server) history() historyStore {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return historyStore{dir: s.cfg.RunsDir}
+}
+
+// handleHistory lists stored runs (GET).
+func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request) {
+	recs, err := s.history().list()
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	if recs == nil {
+		recs = []runRecord{}
+	}
+	writeJSON(w, map[string]any{"runs": recs})
+}
+
+// handleHistoryRun returns one stored run with its report and findings (GET), or deletes it (DELETE).
+func (s *Server) handleHistoryRun(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	h := s.history()
+	switch r.Method {
+	case http.MethodGet:
+		rec, err := h.load(id)
+		if err != nil {
+			httpErrorFor(w, err)
+			return
+		}
+		writeJSON(w, map[string]any{"run": rec, "markdown": rec.Markdown, "findings": rec.Findings})
+	case http.MethodDelete:
+		s.mu.Lock()
+		if res, ok := s.results[id]; ok && (res.State == runRunning || res.State == runQueued) {
+			s.mu.Unlock()
+			http.Error(w, "run still in progress", 409)
+			return
+		}
+		delete(s.results, id)
+		for i, rid := range s.resultIDs {
+			if rid == id {
+				s.resultIDs = append(s.resultIDs[:i], s.resultIDs[i+1:]...)
+				break
+			}
+		}
+		s.mu.Unlock()
+		s.runsMu.Lock()
+		delete(s.runs, id)
+		s.runsMu.Unlock()
+		if err := h.delete(id); err != nil {
+			httpErrorFor(w, err)
+			return
+		}
+		writeJSON(w, map[string]any{"ok": true})
+	default:
+		http.Error(w, "GET or DELETE only", 405)
+	}
+}
+
+func httpErrorFor(w http.ResponseWriter, err error) {
+	if errors.Is(err, os.ErrNotExist) {
+		http.Error(w, "unknown run", 404)
+		return
+	}
+	http.Error(w, err.Error(), 400)
+}
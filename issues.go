@@ -0,0 +1,133 @@
+// issues.go
+// `type: issues` and `type: pulls` searches via GitHub's issues search endpoint, over
+// updated: windows. Questions like "how do I handle Polygon websocket reconnects"
+// are the signal these are for.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+type IssueHit struct {
+	Group      string     `json:"group"`
+	QueryName  string     `json:"queryName"`
+	Kind       string     `json:"kind"` // "issue" or "pull"
+	Repository string     `json:"repository"`
+	RepoURL    string     `json:"repoUrl"`
+	Number     int        `json:"number"`
+	Title      string     `json:"title"`
+	HTMLURL    string     `json:"htmlUrl"`
+	State      string     `json:"state"`
+	Labels     []string   `json:"labels"`
+	Comments   int        `json:"comments"`
+	Author     string     `json:"author"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	UpdatedAt  time.Time  `json:"updatedAt"`
+	FirstSeen  time.Time  `json:"firstSeen"`
+	LastSeen   time.Time  `json:"lastSeen"`
+	IsNew      bool       `json:"isNew"`
+	Score      float64    `json:"score"`
+	MatchedBy  []QueryRef `json:"matchedBy"`
+}
+
+type issueSearchResp struct {
+	TotalCount        int         `json:"total_count"`
+	IncompleteResults bool        `json:"incomplete_results"`
+	Items             []issueItem `json:"items"`
+}
+type issueItem struct {
+	HTMLURL       string `json:"html_url"`
+	RepositoryURL string `json:"repository_url"`
+	Number        int    `json:"number"`
+	Title         string `json:"title"`
+	State         string `json:"state"`
+	Comments      int    `json:"comments"`
+	CreatedAt     string `json:"created_at"`
+	UpdatedAt     string `json:"updated_at"`
+	User          struct {
+		Login string `json:"login"`
+	} `json:"user"`
+	Labels []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+	PullRequest *struct{} `json:"pull_request"`
+}
+
+// dateQual matches a created:/updated: qualifier the user already put in a query.
+var dateQual = regexp.MustCompile(`(?i)\b(created|updated):`)
+
+// searchIssues runs an issue query over updated: slices; kind is "issues" or "pulls".
+// A query with its own created:/updated: qualifier runs as-is. See windowedSearch.
+func searchIssues(ctx context.Context, client *ghClient, cfg AppSettings, g SearchGroup, q SearchQuery, kind string, since time.Time, emit func(DebugEvent)) ([]IssueHit, []string, error) {
+	var hits []IssueHit
+	is := "is:issue"
+	if kind == "pulls" {
+		is = "is:pr"
+	}
+	ws := windowedSearch{kind: kind, endpoint: "issues", sort: "updated", field: "updated", query: q.Query + " " + is,
+		decode: func(body []byte) (int, bool, int, error) {
+			var ir issueSearchResp
+			if err := json.Unmarshal(body, &ir); err != nil {
+				return 0, false, 0, err
+			}
+			for _, it := range ir.Items {
+				hits = append(hits, issueHitFrom(client, it, g, q))
+			}
+			return ir.TotalCount, ir.IncompleteResults, len(ir.Items), nil
+		}}
+	if dateQual.MatchString(q.Query) {
+		ws.field = ""
+	}
+	notes, err := ws.run(ctx, client, cfg, g, q, since, emit)
+	if err == nil && len(hits) == 0 {
+		notes = append(notes, fmt.Sprintf("No %s hits for %s — %s", kind, g.Name, q.Name))
+		emit(DebugEvent{Phase: "search-" + kind + "-empty", Group: g.Name, QueryName: q.Name, Note: "no " + kind + " hits"})
+	}
+	return hits, notes, err
+}
+
+func issueHitFrom(client *ghClient, it issueItem, g SearchGroup, q SearchQuery) IssueHit {
+	created, _ := time.Parse(time.RFC3339, it.CreatedAt)
+	updated, _ := time.Parse(time.RFC3339, it.UpdatedAt)
+	// repository_url is <apiBase>/repos/{owner}/{repo}
+	repo := it.RepositoryURL
+	if i := strings.Index(repo, "/repos/"); i >= 0 {
+		repo = repo[i+len("/repos/"):]
+	}
+	kind := "issue"
+	if it.PullRequest != nil {
+		kind = "pull"
+	}
+	labels := make([]string, 0, len(it.Labels))
+	for _, l := range it.Labels {
+		labels = append(labels, l.Name)
+	}
+	return IssueHit{
+		Group:      g.Name,
+		QueryName:  q.Name,
+		Kind:       kind,
+		Repository: repo,
+		RepoURL:    client.webURL(repo),
+		Number:     it.Number,
+		Title:      it.Title,
+		HTMLURL:    it.HTMLURL,
+		State:      it.State,
+		Labels:     labels,
+		Comments:   it.Comments,
+		Author:     it.User.Login,
+		CreatedAt:  created,
+		UpdatedAt:  updated,
+	}
+}
+
+func dedupeIssue(in []IssueHit) []IssueHit {
+	return mergeHits(in, issueKey, func(h *IssueHit) (*[]QueryRef, QueryRef) { return &h.MatchedBy, QueryRef{h.Group, h.QueryName} }, nil)
+}
+
+func issueKey(h IssueHit) string { return h.HTMLURL }
@@ -1,7 +1,7 @@
 // main.go
 // gh-api-watch: Daily GitHub watcher for Polygon.io, Alpaca, IBKR, Databento (and anything else in queries.yaml).
 // - CLI launches a local web UI on http://localhost:8084
-// - Requires .env with GITHUB_TOKEN and OPENAI_API_KEY
+// - Requires .env with GITHUB_TOKEN (or GitHub App credentials) and OPENAI_API_KEY
 // - Does nothing until you Save Settings, then Run report.
 // - Report drafted by OpenAI and displayed as Markdown with a Raw/Pretty toggle (+ copy button).
 
@@ -46,70 +46,213 @@ type AppSettings struct {
 	MaxPages         int    `json:"maxPages"`         // safety cap per search
 	PerPage          int    `json:"perPage"`          // items per page
 	UseCommitCheck   bool   `json:"useCommitCheck"`   // try to verify file recency via Commits API
+	CommitCheckMode  string `json:"commitCheckMode"`  // "graphql" (batched, REST fallback) or "rest"
+	EnrichRepos      bool   `json:"enrichRepos"`      // fetch stars/license/topics for repos with code hits
+	Scoring          ScoreWeights `json:"scoring"`    // relevance weights for ranking hits
 	IncludeRepoSearch bool  `json:"includeRepoSearch"`// include repo-level searches
 	QueriesFile      string `json:"queriesFile"`
+	SeenFile         string `json:"seenFile"`         // persistent first/last-seen store
+	NewOnly          bool   `json:"newOnly"`          // report only hits never seen before
+	SkipOpenAI       bool   `json:"skipOpenAI"`       // headless runs: skip drafting, emit the fallback report
+	Schedules        []string `json:"schedules"`      // cron expressions for recurring runs in `serve`
+	MaxParallelRuns  int    `json:"maxParallelRuns"`  // runs allowed at once in `serve`
+	RunPolicy        string `json:"runPolicy"`        // "queue" or "reject" when all slots are busy
+	RunsDir          string `json:"runsDir"`          // run history (settings, findings, report, debug log per run)
+	CacheDir         string `json:"cacheDir"`         // conditional-request cache for GitHub GETs
+}
+
+func defaultSettings() AppSettings {
+	return AppSettings{
+		DaysBack:          defaultDaysBack,
+		OpenAIModel:       defaultModel,
+		MaxPages:          maxPagesDefault,
+		PerPage:           perPageDefault,
+		UseCommitCheck:    true,
+		CommitCheckMode:   commitCheckGraphQL,
+		EnrichRepos:       true,
+		Scoring:           defaultScoreWeights(),
+		IncludeRepoSearch: true,
+		QueriesFile:       defaultQueriesFile,
+		SeenFile:          defaultSeenFile,
+		MaxParallelRuns:   1,
+		RunPolicy:         policyQueue,
+		RunsDir:           defaultRunsDir,
+		CacheDir:          defaultCacheDir,
+	}
+}
+
+// normalize enforces sane bounds, falling back to defaults for out-of-range values.
+func (c *AppSettings) normalize() {
+	if c.DaysBack < 1 || c.DaysBack > 365 {
+		c.DaysBack = defaultDaysBack
+	}
+	if c.MaxPages < 1 || c.MaxPages > 10 {
+		c.MaxPages = maxPagesDefault
+	}
+	if c.PerPage < 10 || c.PerPage > 100 {
+		c.PerPage = perPageDefault
+	}
+	if c.OpenAIModel == "" {
+		c.OpenAIModel = defaultModel
+	}
+	if c.QueriesFile == "" {
+		c.QueriesFile = defaultQueriesFile
+	}
+	if c.SeenFile == "" {
+		c.SeenFile = defaultSeenFile
+	}
+	// parallel runs share one token's search budget; more than a few just contend
+	if c.MaxParallelRuns < 1 || c.MaxParallelRuns > 4 {
+		c.MaxParallelRuns = 1
+	}
+	if c.CommitCheckMode != commitCheckREST {
+		c.CommitCheckMode = commitCheckGraphQL
+	}
+	if c.RunPolicy != policyReject {
+		c.RunPolicy = policyQueue
+	}
+	if c.RunsDir == "" {
+		c.RunsDir = defaultRunsDir
+	}
+	if c.CacheDir == "" {
+		c.CacheDir = defaultCacheDir
+	}
 }
 
 type SearchQuery struct {
 	Name    string `yaml:"name"`
-	Type    string `yaml:"type"`   // "code" or "repo"
-	Query   string `yaml:"query"`  // raw GitHub search query (no date filter; we apply it for repo)
+	Type    string `yaml:"type"`   // "code", "repo", "commits", "issues" or "pulls"
+	Query   string `yaml:"query"`  // raw GitHub search query (no date filter; we apply it for repo, commits, issues and pulls)
 	Enabled bool   `yaml:"enabled"`
 }
 
 type SearchGroup struct {
 	Name     string        `yaml:"name"`
 	Enabled  bool          `yaml:"enabled"`
+	APIBase  string        `yaml:"apiBase"`  // GitHub Enterprise Server, e.g. https://ghe.example.com/api/v3; default api.github.com
+	TokenEnv string        `yaml:"tokenEnv"` // env var holding this group's token; default GITHUB_TOKEN
+	Exclude  ExcludeRules  `yaml:"exclude"`  // on top of the spec-wide rules
 	Searches []SearchQuery `yaml:"searches"`
 }
 
 type QueriesSpec struct {
-	Groups []SearchGroup `yaml:"groups"`
+	Exclude ExcludeRules  `yaml:"exclude"` // applied to every group
+	Groups  []SearchGroup `yaml:"groups"`
 }
 
 type CodeHit struct {
-	Group       string    `json:"group"`
-	QueryName   string    `json:"queryName"`
-	Repository  string    `json:"repository"`
-	RepoURL     string    `json:"repoUrl"`
-	FilePath    string    `json:"filePath"`
-	FileURL     string    `json:"fileUrl"`
-	Language    string    `json:"language"`
-	RepoPushed  time.Time `json:"repoPushed"`
-	CommitDate  time.Time `json:"commitDate"` // if verified
+	Group      string        `json:"group"`
+	QueryName  string        `json:"queryName"`
+	Repository string        `json:"repository"`
+	RepoURL    string        `json:"repoUrl"`
+	FilePath   string        `json:"filePath"`
+	FileURL    string        `json:"fileUrl"`
+	Language   string        `json:"language"`
+	RepoPushed time.Time     `json:"repoPushed"`
+	RepoMeta   *RepoMeta     `json:"repoMeta,omitempty"` // if enriched
+	CommitDate time.Time     `json:"commitDate"`         // if verified
+	Snippets   []CodeSnippet `json:"snippets,omitempty"` // matched fragments, from text-match search
+	FirstSeen  time.Time     `json:"firstSeen"`
+	LastSeen   time.Time     `json:"lastSeen"`
+	IsNew      bool          `json:"isNew"`
+	Score      float64       `json:"score"`     // see rankFindings
+	MatchedBy  []QueryRef    `json:"matchedBy"` // every search that found this file; Group/QueryName is the first
 }
 
 type RepoHit struct {
-	Group       string    `json:"group"`
-	QueryName   string    `json:"queryName"`
-	FullName    string    `json:"fullName"`
-	HTMLURL     string    `json:"htmlUrl"`
-	Description string    `json:"description"`
-	PushedAt    time.Time `json:"pushedAt"`
-	CreatedAt   time.Time `json:"createdAt"`
+	Group       string     `json:"group"`
+	QueryName   string     `json:"queryName"`
+	FullName    string     `json:"fullName"`
+	HTMLURL     string     `json:"htmlUrl"`
+	Description string     `json:"description"`
+	PushedAt    time.Time  `json:"pushedAt"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	Stars       int        `json:"stars"`
+	Archived    bool       `json:"archived"`
+	Fork        bool       `json:"fork"`
+	FirstSeen   time.Time  `json:"firstSeen"`
+	LastSeen    time.Time  `json:"lastSeen"`
+	IsNew       bool       `json:"isNew"`
+	Score       float64    `json:"score"` // see rankFindings
+	MatchedBy   []QueryRef `json:"matchedBy"`
 }
 
 type Findings struct {
-	RunID      string    `json:"runId"`
-	SinceISO   string    `json:"sinceIso"`
-	DaysBack   int       `json:"daysBack"`
-	Generated  string    `json:"generated"`
-	CodeHits   []CodeHit `json:"codeHits"`
-	RepoHits   []RepoHit `json:"repoHits"`
-	Notes      []string  `json:"notes"`
+	RunID      string      `json:"runId"`
+	SinceISO   string      `json:"sinceIso"`
+	DaysBack   int         `json:"daysBack"`
+	Generated  string      `json:"generated"`
+	CodeHits   []CodeHit   `json:"codeHits"`
+	RepoHits   []RepoHit   `json:"repoHits"`
+	CommitHits []CommitHit `json:"commitHits"`
+	IssueHits  []IssueHit  `json:"issueHits"` // issues and pull requests
+	Notes      []string    `json:"notes"`
 }
 
 type Server struct {
 	cfg      AppSettings
 	mu       sync.RWMutex
 	saved    bool
-	markdown string
-	raw      Findings
-	status   string
-	inProgress bool
+	settingsFile string // where Save settings persists cfg
 	lastRunID string
 	runsMu    sync.RWMutex
 	runs      map[string][]DebugEvent
+	results   map[string]*runResult // guarded by mu; each run owns its status and output
+	resultIDs []string              // oldest first, capped at maxKeptResults
+	queue     []pendingRun          // guarded by mu; admitted runs waiting for a free slot
+	sched     schedState            // guarded by mu
+	schedWake chan struct{}
+}
+
+const maxKeptResults = 50
+
+// Run states reported by /api/run.
+const (
+	runQueued    = "queued"
+	runRunning   = "running"
+	runDone      = "done"
+	runFailed    = "failed"
+	runCancelled = "cancelled"
+)
+
+// Admission policies for a run requested while MaxParallelRuns are already running.
+const (
+	policyQueue  = "queue"  // wait for a free slot
+	policyReject = "reject" // refuse with 409
+)
+
+// errRunRejected is returned by startRun when the reject policy turns a run away.
+var errRunRejected = errors.New("run rejected: too many runs in progress")
+
+type pendingRun struct {
+	ctx  context.Context
+	res  *runResult
+	cfg  AppSettings
+	spec *QueriesSpec
+}
+
+// runResult is one background run: its progress while running, its outcome afterwards.
+type runResult struct {
+	ID       string    `json:"id"`
+	Trigger  string    `json:"trigger"` // "manual" or "schedule <expr>"
+	State    string    `json:"state"`
+	Status   string    `json:"status"` // human-readable progress line
+	Queued   time.Time `json:"queued"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	Error    string    `json:"error,omitempty"`
+	Fallback bool      `json:"fallback"`
+	Markdown string    `json:"-"`
+	Findings Findings  `json:"-"`
+	cancel   context.CancelFunc
+	saved    bool // written to history; evictResults may drop it and its debug events
+}
+
+type schedState struct {
+	next      time.Time
+	nextExpr  string
+	last      time.Time
+	lastRunID string
 }
 
 // DebugEvent is a structured, per-request/per-phase log entry.
@@ -131,23 +274,17 @@ func main() {
 	// Load .env like python-dotenv
 	_ = godotenv.Load()
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = defaultPort
-	}
+	os.Exit(runCLI(os.Args[1:]))
+}
 
-	s := &Server{
-		cfg: AppSettings{
-			DaysBack:          defaultDaysBack,
-			OpenAIModel:       defaultModel,
-			MaxPages:          maxPagesDefault,
-			PerPage:           perPageDefault,
-			UseCommitCheck:    true,
-			IncludeRepoSearch: true,
-			QueriesFile:       defaultQueriesFile,
-		},
-	}
+// serve starts the web UI and blocks until the HTTP server stops. Settings that came
+// from a settings file or GHW_* variables count as saved, so Run report works right away.
+func serve(cfg AppSettings, opts runOptions) error {
+	s := &Server{cfg: cfg, saved: opts.settingsSet, settingsFile: opts.settingsFile}
 	s.runs = make(map[string][]DebugEvent)
+	s.results = make(map[string]*runResult)
+	s.schedWake = make(chan struct{}, 1)
+	go s.runScheduler(context.Background())
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", s.handleIndex)
@@ -156,31 +293,59 @@ func main() {
 	mux.HandleFunc("/api/get-queries", s.handleGetQueries)
 	mux.HandleFunc("/api/save-queries", s.handleSaveQueries)
 	mux.HandleFunc("/api/run-report", s.handleRunReport)
+	mux.HandleFunc("/api/run", s.handleRun)
+	mux.HandleFunc("/api/run/markdown", s.handleRunMarkdown)
+	mux.HandleFunc("/api/run/findings", s.handleRunFindings)
+	mux.HandleFunc("/api/cancel", s.handleCancel)
+	mux.HandleFunc("/api/history", s.handleHistory)
+	mux.HandleFunc("/api/history/run", s.handleHistoryRun)
+	mux.HandleFunc("/api/diff", s.handleDiff)
 	mux.HandleFunc("/api/status", s.handleStatus)
 	mux.HandleFunc("/api/debug", s.handleDebug)
 	mux.HandleFunc("/api/runs", s.handleRuns)
 	mux.HandleFunc("/api/last-raw", func(w http.ResponseWriter, r *http.Request){
 		s.mu.RLock(); defer s.mu.RUnlock()
-		writeJSON(w, s.raw)
+		for i := len(s.resultIDs) - 1; i >= 0; i-- {
+			if res := s.results[s.resultIDs[i]]; res.State == runDone {
+				writeJSON(w, res.Findings)
+				return
+			}
+		}
+		// nothing this session: fall back to the newest completed run on disk
+		h := historyStore{dir: s.cfg.RunsDir}
+		recs, _ := h.list()
+		for _, rec := range recs {
+			if rec.State != runDone {
+				continue
+			}
+			if full, err := h.load(rec.ID); err == nil {
+				writeJSON(w, full.Findings)
+				return
+			}
+		}
+		writeJSON(w, Findings{})
 	})
 	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(200); _, _ = w.Write([]byte("ok")) })
 
 	srv := &http.Server{
-		Addr:              "127.0.0.1:" + port,
+		Addr:              "127.0.0.1:" + opts.port,
 		Handler:           withCORS(mux),
 		ReadHeaderTimeout: 10 * time.Second,
 	}
 
-	url := "http://localhost:" + port + "/"
-	go func() {
-		time.Sleep(300 * time.Millisecond)
-		_ = exec.Command("xdg-open", url).Start()
-	}()
+	url := "http://localhost:" + opts.port + "/"
+	if !opts.noOpen {
+		go func() {
+			time.Sleep(300 * time.Millisecond)
+			_ = exec.Command("xdg-open", url).Start()
+		}()
+	}
 
 	log.Printf("gh-api-watch listening on %s", url)
 	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-		log.Fatal(err)
+		return err
 	}
+	return nil
 }
 
 func withCORS(next http.Handler) http.Handler {
@@ -213,7 +378,7 @@ func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
 .container{max-width:1120px;margin:32px auto;padding:0 16px}
 h1{font-size:1.6rem;margin:0 0 8px} .sub{color:var(--muted);margin-bottom:24px}
 .card{background:var(--card);border:1px solid #1f263d;border-radius:10px;padding:16px;margin-bottom:16px}
-label{display:block;margin:8px 0 4px} input[type=number],input[type=text],textarea{width:100%;padding:10px;border:1px solid #2b3553;border-radius:8px;background:#0e1426;color:var(--fg)}
+label{display:block;margin:8px 0 4px} input[type=number],input[type=text],textarea,select{width:100%;padding:10px;border:1px solid #2b3553;border-radius:8px;background:#0e1426;color:var(--fg)}
 .row{display:grid;grid-template-columns:repeat(4,1fr);gap:12px}
 .actions{display:flex;gap:12px;flex-wrap:wrap;margin-top:12px}
 button{background:var(--acc);color:#081022;border:0;padding:10px 14px;border-radius:8px;cursor:pointer;font-weight:600}
@@ -234,7 +399,7 @@ kbd{background:#11182d;border:1px solid #2b3553;border-bottom-color:#1d2743;bord
 
   <div class="card">
     <div><span class="badge" id="envGH">GitHub: …</span><span class="badge" id="envOA">OpenAI: …</span><span class="badge" id="saved">Settings: not saved</span></div>
-    <p class="small">Keys must be in <code>.env</code> alongside the binary: <code>GITHUB_TOKEN</code> and <code>OPENAI_API_KEY</code>.</p>
+    <p class="small">Keys must be in <code>.env</code> alongside the binary: <code>GITHUB_TOKEN</code> (or <code>GITHUB_APP_ID</code> + private key) and <code>OPENAI_API_KEY</code>.</p>
     <div class="row">
       <div>
         <label>Days back</label>
@@ -257,6 +422,17 @@ kbd{background:#11182d;border:1px solid #2b3553;border-bottom-color:#1d2743;bord
       <div>
         <label><input id="useCommitCheck" type="checkbox" checked/> Verify file recency via Commits API</label>
       </div>
+      <div>
+        <label>Recency lookups</label>
+        <select id="commitCheckMode"><option value="graphql">GraphQL, batched</option><option value="rest">REST, one per file</option></select>
+      </div>
+      <div>
+        <label><input id="enrichRepos" type="checkbox" checked/> Fetch repo metadata (stars, license, topics)</label>
+      </div>
+      <div>
+        <label>Score weights</label>
+        <input id="scoring" type="text" placeholder="recency=1,stars=1,queries=1,path=1,org=0.25"/>
+      </div>
       <div>
         <label><input id="includeRepoSearch" type="checkbox" checked/> Include repo (README/desc) searches</label>
       </div>
@@ -264,10 +440,29 @@ kbd{background:#11182d;border:1px solid #2b3553;border-bottom-color:#1d2743;bord
         <label>Queries file</label>
         <input id="queriesFile" type="text" value="queries.yaml"/>
       </div>
+      <div>
+        <label><input id="newOnly" type="checkbox"/> Report only new (first-seen) hits</label>
+      </div>
+    </div>
+    <div class="row" style="margin-top:8px">
+      <div>
+        <label>Parallel runs</label>
+        <input id="maxParallelRuns" type="number" min="1" max="4" value="1"/>
+      </div>
+      <div>
+        <label>When all run slots are busy</label>
+        <select id="runPolicy"><option value="queue">Queue the run</option><option value="reject">Reject the run</option></select>
+      </div>
+    </div>
+    <div style="margin-top:8px">
+      <label>Schedules (cron, one per line, local time — e.g. <code>0 7 * * *</code> or <code>@weekly</code>)</label>
+      <textarea id="schedules" rows="2" spellcheck="false"></textarea>
+      <p class="small" id="schedInfo"></p>
     </div>
     <div class="actions">
       <button id="saveBtn">Save settings</button>
       <button id="runBtn" disabled>Run report</button>
+      <button class="secondary" id="cancelBtn" disabled>Cancel run</button>
     </div>
   </div>
 
@@ -288,6 +483,7 @@ kbd{background:#11182d;border:1px solid #2b3553;border-bottom-color:#1d2743;bord
       <button class="secondary" id="copy">Copy Raw Markdown</button>
     </div>
     <p class="small" id="status">Idle.</p>
+    <p class="small" id="rateInfo"></p>
     <hr/>
     <div id="pretty" style="display:none">
       <div id="preview">No report yet.</div>
@@ -297,6 +493,24 @@ kbd{background:#11182d;border:1px solid #2b3553;border-bottom-color:#1d2743;bord
     </div>
   </div>
 
+  <div class="card">
+    <h3>History</h3>
+    <p class="small">Past runs stored under <code>runs/</code>. Open one to show its report above.</p>
+    <div class="actions"><button class="secondary" id="reloadH">Refresh</button></div>
+    <table id="history" style="width:100%;margin-top:8px;font-size:.9rem"></table>
+  </div>
+
+  <div class="card">
+    <h3>Compare runs</h3>
+    <p class="small">What changed between two stored runs: new and disappeared repos/files, moved push dates.</p>
+    <div class="row">
+      <div><label>From (older)</label><select id="diffFrom"></select></div>
+      <div><label>To (newer)</label><select id="diffTo"></select></div>
+    </div>
+    <div class="actions"><button class="secondary" id="diffBtn">Compare</button></div>
+    <div id="diff" style="margin-top:8px"></div>
+  </div>
+
   <p class="small"><a href="/api/last-raw" target="_blank">View diagnostics JSON</a></p>
   <p class="small">Links open in a new tab. Queries are executed only when you press <strong>Run report</strong>.</p>
 </div>
@@ -306,14 +520,22 @@ async function getEnv(){
   const r = await fetch('/api/get-env'); const j = await r.json();
   document.getElementById('envGH').textContent = 'GitHub: ' + (j.github?'✓ found':'missing');
   document.getElementById('envOA').textContent = 'OpenAI: ' + (j.openai?'✓ found':'missing');
-  document.getElementById('saved').textContent = 'Settings: ' + (j.saved?'saved':'not saved');
+  document.getElementById('saved').textContent = 'Settings: ' + (j.saved?'saved to '+j.settingsFile:'not saved');
   document.getElementById('daysBack').value = j.settings.daysBack;
   document.getElementById('model').value = j.settings.openAIModel;
   document.getElementById('maxPages').value = j.settings.maxPages;
   document.getElementById('perPage').value = j.settings.perPage;
   document.getElementById('useCommitCheck').checked = j.settings.useCommitCheck;
+  document.getElementById('commitCheckMode').value = j.settings.commitCheckMode;
+  document.getElementById('enrichRepos').checked = j.settings.enrichRepos;
+  const w = j.settings.scoring || {};
+  document.getElementById('scoring').value = ['recency','stars','queries','path','org'].map(k => k + '=' + (w[k] ?? 0)).join(',');
   document.getElementById('includeRepoSearch').checked = j.settings.includeRepoSearch;
   document.getElementById('queriesFile').value = j.settings.queriesFile;
+  document.getElementById('newOnly').checked = j.settings.newOnly;
+  document.getElementById('maxParallelRuns').value = j.settings.maxParallelRuns;
+  document.getElementById('runPolicy').value = j.settings.runPolicy;
+  document.getElementById('schedules').value = (j.settings.schedules || []).join('\n');
   document.getElementById('runBtn').disabled = !j.saved;
 }
 async function loadQueries(){
@@ -330,11 +552,19 @@ document.getElementById('saveBtn').onclick = async ()=>{
     maxPages: +document.getElementById('maxPages').value,
     perPage: +document.getElementById('perPage').value,
     useCommitCheck: document.getElementById('useCommitCheck').checked,
+    commitCheckMode: document.getElementById('commitCheckMode').value,
+    enrichRepos: document.getElementById('enrichRepos').checked,
+    scoring: Object.fromEntries(document.getElementById('scoring').value.split(',').map(p => p.split('=')).filter(kv => kv.length === 2).map(([k, v]) => [k.trim(), +v])),
     includeRepoSearch: document.getElementById('includeRepoSearch').checked,
-    queriesFile: document.getElementById('queriesFile').value.trim()
+    queriesFile: document.getElementById('queriesFile').value.trim(),
+    newOnly: document.getElementById('newOnly').checked,
+    maxParallelRuns: +document.getElementById('maxParallelRuns').value,
+    runPolicy: document.getElementById('runPolicy').value,
+    schedules: document.getElementById('schedules').value.split('\n').map(s=>s.trim()).filter(Boolean)
   };
   const r = await fetch('/api/save-settings',{method:'POST',headers:{'Content-Type':'application/json'},body:JSON.stringify(payload)});
-  const j = await r.json(); if(j.ok){ await getEnv(); await loadQueries(); }
+  if(!r.ok){ alert(await r.text()); return; }
+  const j = await r.json(); if(j.ok){ await getEnv(); await loadQueries(); await pollStatus(); }
 };
 
 document.getElementById('saveQ').onclick = async ()=>{
@@ -350,36 +580,119 @@ async function pollStatus(){
     const r = await fetch('/api/status');
     const j = await r.json();
     document.getElementById('status').textContent = j.status || (j.inProgress? 'Working…' : 'Idle.');
-    if(!j.inProgress && statusTimer){ clearInterval(statusTimer); statusTimer = undefined; }
+    const sc = j.schedule || {};
+    document.getElementById('schedInfo').textContent = sc.nextRun
+      ? 'Next scheduled run: ' + sc.nextRun + ' (' + sc.nextExpr + ')' + (sc.lastRun? ' · last: ' + sc.lastRun : '')
+      : (sc.lastRun? 'Last scheduled run: ' + sc.lastRun : 'No schedule.');
+    document.getElementById('rateInfo').textContent = (j.rateLimit || []).map(b =>
+      (b.apiBase === 'https://api.github.com' ? 'GitHub' : b.apiBase) + ' ' + b.resource + ': ' + (b.remaining < 0 ? '?' : b.remaining) + '/' + b.limit + (b.blockedUntil ? ' (paused until ' + b.blockedUntil + ')' : '')).join(' · ');
+    if(j.inProgress && j.runId && !watching){ watchRun(j.runId); }
   }catch(e){}
 }
+function showMarkdown(md){
+  document.getElementById('md').textContent = md || '(empty)';
+  document.getElementById('preview').innerHTML = marked.parse(md || '');
+  // Ensure all links open in new tab
+  const pv = document.getElementById('preview');
+  pv.querySelectorAll('a[href]')?.forEach(a=>{ a.target = '_blank'; a.rel = 'noopener noreferrer'; });
+}
+// Runs execute in the background; poll the run until it finishes, then fetch its report.
+let watching;
+async function watchRun(id){
+  watching = id;
+  document.getElementById('runBtn').disabled = true;
+  document.getElementById('cancelBtn').disabled = false;
+  try{
+    for(;;){
+      const r = await fetch('/api/run?id='+encodeURIComponent(id));
+      if(!r.ok){ document.getElementById('status').textContent = 'Error: ' + await r.text(); break; }
+      const j = await r.json();
+      document.getElementById('status').textContent = '[' + j.id + '] ' + (j.status || j.state);
+      if(j.state !== 'running' && j.state !== 'queued'){
+        if(j.state === 'done' || j.state === 'cancelled'){
+          const m = await fetch('/api/run/markdown?id='+encodeURIComponent(id));
+          showMarkdown(await m.text());
+        }
+        break;
+      }
+      await new Promise(res=>setTimeout(res, 1000));
+    }
+  }catch(e){
+    document.getElementById('status').textContent = 'Error: ' + (e && e.message? e.message : e);
+  } finally {
+    watching = undefined;
+    document.getElementById('runBtn').disabled = false;
+    document.getElementById('cancelBtn').disabled = true;
+    loadHistory();
+  }
+}
+document.getElementById('cancelBtn').onclick = async ()=>{
+  if(!watching) return;
+  const r = await fetch('/api/cancel?id='+encodeURIComponent(watching),{method:'POST'});
+  if(!r.ok){ document.getElementById('status').textContent = 'Error: ' + await r.text(); }
+};
 document.getElementById('runBtn').onclick = async ()=>{
   document.getElementById('runBtn').disabled = true;
   document.getElementById('status').textContent = 'Starting…';
-  statusTimer = setInterval(pollStatus, 700);
-  let hadErr = false;
   try{
     const r = await fetch('/api/run-report',{method:'POST'});
     if(!r.ok){
-      const txt = await r.text();
-      document.getElementById('status').textContent = 'Error: ' + txt;
-      hadErr = true;
-    } else {
-      const j = await r.json();
-      document.getElementById('md').textContent = j.markdown || '(empty)';
-      document.getElementById('preview').innerHTML = marked.parse(j.markdown || '');
-      // Ensure all links open in new tab
-      const pv = document.getElementById('preview');
-      pv.querySelectorAll('a[href]')?.forEach(a=>{ a.target = '_blank'; a.rel = 'noopener noreferrer'; });
+      document.getElementById('status').textContent = 'Error: ' + await r.text();
+      document.getElementById('runBtn').disabled = false;
+      return;
     }
+    const j = await r.json();
+    await watchRun(j.runId);
   }catch(e){
     document.getElementById('status').textContent = 'Error: ' + (e && e.message? e.message : e);
-    hadErr = true;
-  } finally {
     document.getElementById('runBtn').disabled = false;
-    if (!hadErr) await pollStatus();
   }
 };
+statusTimer = setInterval(pollStatus, 5000);
+
+function esc(s){ return String(s ?? '').replace(/[&<>"]/g, c=>({'&':'&amp;','<':'&lt;','>':'&gt;','"':'&quot;'}[c])); }
+async function loadHistory(){
+  const r = await fetch('/api/history'); const j = await r.json();
+  const rows = (j.runs || []).map(run =>
+    '<tr><td>' + esc(run.id) + '</td><td>' + esc(run.trigger) + '</td><td>' + esc(run.state) + '</td>' +
+    '<td>' + esc(run.finished) + '</td>' +
+    '<td><button class="secondary" data-open="' + esc(run.id) + '">Open</button> ' +
+    '<a href="/api/history/run?id=' + encodeURIComponent(run.id) + '" target="_blank">JSON</a> ' +
+    '<button class="secondary" data-del="' + esc(run.id) + '">Delete</button></td></tr>');
+  const opts = (j.runs || []).filter(run => run.state === 'done')
+    .map(run => '<option value="' + esc(run.id) + '">' + esc(run.id) + ' (' + esc(run.trigger) + ')</option>').join('');
+  document.getElementById('diffFrom').innerHTML = opts;
+  document.getElementById('diffTo').innerHTML = opts;
+  if(document.getElementById('diffFrom').options.length > 1){ document.getElementById('diffFrom').selectedIndex = 1; }
+  document.getElementById('history').innerHTML = rows.length
+    ? '<tr><th align="left">Run</th><th align="left">Trigger</th><th align="left">State</th><th align="left">Finished</th><th></th></tr>' + rows.join('')
+    : '<tr><td class="small">No stored runs.</td></tr>';
+}
+document.getElementById('history').onclick = async (e)=>{
+  const open = e.target.dataset.open, del = e.target.dataset.del;
+  if(open){
+    const r = await fetch('/api/history/run?id='+encodeURIComponent(open));
+    if(!r.ok){ alert(await r.text()); return; }
+    const j = await r.json();
+    showMarkdown(j.markdown);
+    document.getElementById('status').textContent = '[' + open + '] ' + (j.run.status || j.run.state) + ' (from history)';
+  }
+  if(del && confirm('Delete run ' + del + '?')){
+    const r = await fetch('/api/history/run?id='+encodeURIComponent(del), {method:'DELETE'});
+    if(!r.ok){ alert(await r.text()); }
+    await loadHistory();
+  }
+};
+document.getElementById('reloadH').onclick = loadHistory;
+document.getElementById('diffBtn').onclick = async ()=>{
+  const from = document.getElementById('diffFrom').value, to = document.getElementById('diffTo').value;
+  const out = document.getElementById('diff');
+  if(!from || !to){ out.textContent = 'Need two completed runs.'; return; }
+  const r = await fetch('/api/diff?format=md&from='+encodeURIComponent(from)+'&to='+encodeURIComponent(to));
+  if(!r.ok){ out.textContent = 'Error: ' + await r.text(); return; }
+  out.innerHTML = marked.parse(await r.text());
+  out.querySelectorAll('a[href]').forEach(a=>{ a.target = '_blank'; a.rel = 'noopener noreferrer'; });
+};
 
 document.getElementById('toggle').onclick = ()=>{
   const raw = document.getElementById('raw'); const pretty = document.getElementById('pretty');
@@ -393,7 +706,7 @@ document.getElementById('copy').onclick = async ()=>{
   alert('Markdown copied to clipboard');
 };
 
-getEnv(); loadQueries();
+getEnv(); loadQueries(); pollStatus(); loadHistory();
 </script>
 </body>
 </html>`
@@ -402,47 +715,70 @@ getEnv(); loadQueries();
 }
 
 func (s *Server) handleGetEnv(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
 	resp := map[string]any{
-		"github":   os.Getenv("GITHUB_TOKEN") != "",
+		"github":   githubAuthProblem() == "",
 		"openai":   os.Getenv("OPENAI_API_KEY") != "",
 		"saved":    s.saved,
+		"settingsFile": s.settingsFile,
 		"settings": s.cfg,
 	}
+	s.mu.RUnlock()
 	writeJSON(w, resp)
 }
 
 func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
 	s.mu.RLock()
-	ip := s.inProgress
-	st := s.status
+	active := []runResult{}
+	for _, id := range s.resultIDs {
+		if res := s.results[id]; res.State == runRunning || res.State == runQueued {
+			active = append(active, *res)
+		}
+	}
+	cur := s.lastRunID
+	st := ""
+	if res, ok := s.results[cur]; ok {
+		st = res.Status
+	}
+	admission := map[string]any{"maxParallelRuns": s.cfg.MaxParallelRuns, "runPolicy": s.cfg.RunPolicy, "queued": len(s.queue)}
+	sched := map[string]any{
+		"schedules": s.cfg.Schedules,
+		"nextRun":   fmtTime(s.sched.next),
+		"nextExpr":  s.sched.nextExpr,
+		"lastRun":   fmtTime(s.sched.last),
+		"lastRunId": s.sched.lastRunID,
+	}
 	s.mu.RUnlock()
-	writeJSON(w, map[string]any{"inProgress": ip, "status": st})
+	writeJSON(w, map[string]any{"inProgress": len(active) > 0, "status": st, "runId": cur, "active": active, "admission": admission, "schedule": sched,
+		"rateLimit": rateBudgets()})
 }
 
 func (s *Server) handleSaveSettings(w http.ResponseWriter, r *http.Request) {
-	var in AppSettings
+	// start from the current settings so fields the UI doesn't edit (seenFile, runsDir) survive
+	s.mu.RLock()
+	in := s.cfg
+	s.mu.RUnlock()
 	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
 		http.Error(w, err.Error(), 400)
 		return
 	}
-	// enforce sane bounds
-	if in.DaysBack < 1 || in.DaysBack > 365 {
-		in.DaysBack = defaultDaysBack
-	}
-	if in.MaxPages < 1 || in.MaxPages > 10 {
-		in.MaxPages = maxPagesDefault
-	}
-	if in.PerPage < 10 || in.PerPage > 100 {
-		in.PerPage = perPageDefault
-	}
-	if in.OpenAIModel == "" {
-		in.OpenAIModel = defaultModel
+	in.normalize()
+	if _, err := parseSchedules(in.Schedules); err != nil {
+		http.Error(w, err.Error(), 400)
+		return
 	}
 	s.mu.Lock()
+	if err := saveSettingsFile(s.settingsFile, in); err != nil {
+		s.mu.Unlock()
+		http.Error(w, "saving "+s.settingsFile+": "+err.Error(), 500)
+		return
+	}
 	s.cfg = in
 	s.saved = true
+	s.dispatchQueued() // a raised MaxParallelRuns may free slots
 	s.mu.Unlock()
-	writeJSON(w, map[string]any{"ok": true})
+	s.wakeScheduler()
+	writeJSON(w, map[string]any{"ok": true, "settingsFile": s.settingsFile})
 }
 
 func (s *Server) handleGetQueries(w http.ResponseWriter, r *http.Request) {
@@ -483,25 +819,340 @@ func (s *Server) handleSaveQueries(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleRunReport(w http.ResponseWriter, r *http.Request) {
-	if !s.saved {
-		http.Error(w, "Save settings first.", 400)
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST only", 405)
 		return
 	}
-	if os.Getenv("GITHUB_TOKEN") == "" || os.Getenv("OPENAI_API_KEY") == "" {
-		http.Error(w, "Missing GITHUB_TOKEN or OPENAI_API_KEY in .env", 400)
+	cfg, spec, err := s.runnable()
+	if err != nil {
+		http.Error(w, err.Error(), 400)
 		return
 	}
-	spec, err := loadQueries(s.cfg.QueriesFile)
+	// The run outlives this request; clients poll /api/run?id=...
+	res, err := s.startRun(cfg, spec, "manual")
 	if err != nil {
-		http.Error(w, "queries.yaml: "+err.Error(), 400)
+		http.Error(w, err.Error(), 409)
 		return
 	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(202)
+	writeJSON(w, map[string]any{"runId": res.ID, "state": res.State})
+}
 
-	runID := newRunID()
+// lookupRun returns a copy of the run named by ?id= (default: the latest run),
+// falling back to the on-disk history for runs from earlier sessions.
+func (s *Server) lookupRun(w http.ResponseWriter, r *http.Request) (runResult, bool) {
+	id := r.URL.Query().Get("id")
+	res, ok := s.findRun(id)
+	if !ok {
+		http.Error(w, "unknown run: "+id, 404)
+	}
+	return res, ok
+}
+
+// findRun is lookupRun without the HTTP plumbing.
+func (s *Server) findRun(id string) (runResult, bool) {
+	s.mu.RLock()
+	if id == "" || id == "last" {
+		id = s.lastRunID
+	}
+	res, ok := s.results[id]
+	var snapshot runResult
+	if ok {
+		snapshot = *res
+	}
+	s.mu.RUnlock()
+	if ok {
+		return snapshot, true
+	}
+	rec, err := s.history().load(id)
+	if err != nil {
+		return runResult{}, false
+	}
+	return rec.runResult, true
+}
+
+func (s *Server) handleRun(w http.ResponseWriter, r *http.Request) {
+	if res, ok := s.lookupRun(w, r); ok {
+		writeJSON(w, res)
+	}
+}
+
+func (s *Server) handleRunMarkdown(w http.ResponseWriter, r *http.Request) {
+	res, ok := s.lookupRun(w, r)
+	if !ok {
+		return
+	}
+	if res.State == runRunning || res.State == runQueued {
+		http.Error(w, "run still in progress", 409)
+		return
+	}
+	w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+	_, _ = w.Write([]byte(res.Markdown))
+}
+
+func (s *Server) handleRunFindings(w http.ResponseWriter, r *http.Request) {
+	res, ok := s.lookupRun(w, r)
+	if !ok {
+		return
+	}
+	if res.State == runRunning || res.State == runQueued {
+		http.Error(w, "run still in progress", 409)
+		return
+	}
+	writeJSON(w, res.Findings)
+}
+
+// runnable returns the saved settings and parsed queries, or why a run can't start.
+func (s *Server) runnable() (AppSettings, *QueriesSpec, error) {
+	s.mu.RLock()
+	cfg, saved := s.cfg, s.saved
+	s.mu.RUnlock()
+	if !saved {
+		return cfg, nil, errors.New("Save settings first.")
+	}
+	if p := githubAuthProblem(); p != "" {
+		return cfg, nil, errors.New("GitHub auth: " + p)
+	}
+	if !cfg.SkipOpenAI && os.Getenv("OPENAI_API_KEY") == "" {
+		return cfg, nil, errors.New("Missing OPENAI_API_KEY in .env")
+	}
+	spec, err := loadQueries(cfg.QueriesFile)
+	if err != nil {
+		return cfg, nil, errors.New("queries.yaml: " + err.Error())
+	}
+	return cfg, spec, nil
+}
+
+func (s *Server) handleCancel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST only", 405)
+		return
+	}
+	id := r.URL.Query().Get("id")
 	s.mu.Lock()
+	if id == "" || id == "last" {
+		id = s.lastRunID
+	}
+	res, ok := s.results[id]
+	if !ok {
+		s.mu.Unlock()
+		http.Error(w, "unknown run: "+id, 404)
+		return
+	}
+	var dequeued *pendingRun
+	switch res.State {
+	case runQueued:
+		// never started: drop it from the queue and finish it here
+		for i, p := range s.queue {
+			if p.res == res {
+				s.queue = append(s.queue[:i], s.queue[i+1:]...)
+				dequeued = &p
+				break
+			}
+		}
+		res.State, res.Status, res.Finished = runCancelled, "Cancelled while queued.", time.Now()
+	case runRunning:
+		res.Status = "Cancelling…"
+	default:
+		s.mu.Unlock()
+		http.Error(w, "run is not in progress: "+res.State, 409)
+		return
+	}
+	snapshot := *res
+	s.mu.Unlock()
+
+	emit := s.emitFunc(id)
+	emit(DebugEvent{Phase: "cancel-requested"})
+	res.cancel()
+	if dequeued != nil {
+		// execRun never sees it, so it is persisted here
+		s.saveHistory(snapshot, dequeued.cfg, emit)
+	}
+	writeJSON(w, map[string]any{"ok": true, "runId": id})
+}
+
+// startRun admits a new run under cfg's parallelism policy: it starts right away,
+// waits in the queue, or is rejected with errRunRejected.
+func (s *Server) startRun(cfg AppSettings, spec *QueriesSpec, trigger string) (runResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	running := s.countRuns(runRunning)
+	if running >= cfg.MaxParallelRuns && cfg.RunPolicy == policyReject {
+		return runResult{}, fmt.Errorf("%w (%d of %d slots busy)", errRunRejected, running, cfg.MaxParallelRuns)
+	}
+
+	runID := newRunID()
+	for n := 2; s.results[runID] != nil; n++ {
+		runID = fmt.Sprintf("%s-%d", newRunID(), n)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	res := &runResult{ID: runID, Trigger: trigger, cancel: cancel}
+	s.results[runID] = res
+	s.resultIDs = append(s.resultIDs, runID)
+	s.evictResults()
 	s.lastRunID = runID
+
+	p := pendingRun{ctx: ctx, res: res, cfg: cfg, spec: spec}
+	if running < cfg.MaxParallelRuns {
+		s.launch(p)
+	} else {
+		res.State, res.Queued = runQueued, time.Now()
+		res.Status = fmt.Sprintf("Queued behind %d run(s).", running+len(s.queue))
+		s.queue = append(s.queue, p)
+	}
+	return *res, nil
+}
+
+// launch marks p running and starts it. Caller holds s.mu.
+func (s *Server) launch(p pendingRun) {
+	p.res.State, p.res.Status, p.res.Started = runRunning, "Starting…", time.Now()
+	go s.execRun(p)
+}
+
+// dispatchQueued starts queued runs while slots are free. Caller holds s.mu.
+func (s *Server) dispatchQueued() {
+	for len(s.queue) > 0 && s.countRuns(runRunning) < s.cfg.MaxParallelRuns {
+		p := s.queue[0]
+		s.queue = s.queue[1:]
+		s.launch(p)
+	}
+}
+
+func (s *Server) countRuns(state string) int {
+	n := 0
+	for _, res := range s.results {
+		if res.State == state {
+			n++
+		}
+	}
+	return n
+}
+
+// evictResults drops the oldest runs beyond maxKeptResults, with their debug events, once
+// they are finished and saved to history. Caller holds s.mu.
+func (s *Server) evictResults() {
+	for i := 0; len(s.resultIDs) > maxKeptResults && i < len(s.resultIDs); {
+		id := s.resultIDs[i]
+		if !s.results[id].saved {
+			i++
+			continue
+		}
+		delete(s.results, id)
+		s.resultIDs = append(s.resultIDs[:i], s.resultIDs[i+1:]...)
+		// handleDebug falls back to the history on disk
+		s.runsMu.Lock()
+		delete(s.runs, id)
+		s.runsMu.Unlock()
+	}
+}
+
+// execRun runs the report pipeline for p, publishing progress on its runResult.
+func (s *Server) execRun(p pendingRun) {
+	res := p.res
+	emit := s.emitFunc(res.ID)
+	emit(DebugEvent{Phase: "trigger", Note: res.Trigger})
+
+	setStatus := func(st string) {
+		s.mu.Lock()
+		res.Status = st
+		s.mu.Unlock()
+	}
+
+	out, err := runReport(p.ctx, p.cfg, p.spec, res.ID, emit, setStatus)
+	res.cancel()
+
+	s.mu.Lock()
+	res.Finished = time.Now()
+	switch {
+	case errors.Is(err, errRunCancelled):
+		res.State = runCancelled
+		res.Status = "Cancelled."
+		res.Markdown, res.Findings, res.Fallback = out.Markdown, out.Findings, out.Fallback
+	case err != nil:
+		res.State = runFailed
+		res.Error = err.Error()
+		res.Status = "Failed: " + err.Error()
+		res.Findings = out.Findings
+	default:
+		res.State = runDone
+		res.Status = "Done."
+		res.Markdown, res.Findings, res.Fallback = out.Markdown, out.Findings, out.Fallback
+	}
+	snapshot := *res
+	s.dispatchQueued()
+	s.mu.Unlock()
+
+	s.saveHistory(snapshot, p.cfg, emit)
+}
+
+// saveHistory writes a finished run and its debug log to cfg's history directory.
+func (s *Server) saveHistory(res runResult, cfg AppSettings, emit func(DebugEvent)) {
+	s.runsMu.RLock()
+	events := append([]DebugEvent(nil), s.runs[res.ID]...)
+	s.runsMu.RUnlock()
+	if err := (historyStore{dir: cfg.RunsDir}).save(res, cfg, events); err != nil {
+		emit(DebugEvent{Phase: "history-error", Note: err.Error()})
+	}
+	s.mu.Lock()
+	if r := s.results[res.ID]; r != nil {
+		r.saved = true
+	}
 	s.mu.Unlock()
-	emit := s.emitFunc(runID)
+}
+
+func (s *Server) handleRuns(w http.ResponseWriter, r *http.Request) {
+    s.runsMu.RLock()
+    ids := make([]string, 0, len(s.runs))
+    for id := range s.runs { ids = append(ids, id) }
+    s.runsMu.RUnlock()
+    sort.Strings(ids)
+    s.mu.RLock()
+    results := make([]runResult, 0, len(s.resultIDs))
+    for _, id := range s.resultIDs { results = append(results, *s.results[id]) }
+    last := s.lastRunID
+    s.mu.RUnlock()
+    writeJSON(w, map[string]any{"runs": ids, "last": last, "results": results})
+}
+
+func (s *Server) handleDebug(w http.ResponseWriter, r *http.Request) {
+    run := r.URL.Query().Get("run")
+    if run == "" || run == "last" {
+        s.mu.RLock()
+        run = s.lastRunID
+        s.mu.RUnlock()
+    }
+    s.runsMu.RLock()
+    evs, ok := s.runs[run]
+    evs = append([]DebugEvent(nil), evs...)
+    s.runsMu.RUnlock()
+    if !ok {
+        evs, _ = s.history().events(run)
+    }
+    writeJSON(w, map[string]any{"runId": run, "events": evs})
+}
+
+// ====== Report pipeline ======
+
+// errRunCancelled is returned by runReport when its parent context is cancelled;
+// the accompanying reportResult still carries the partial findings.
+var errRunCancelled = errors.New("run cancelled")
+
+// reportResult is the outcome of one search + draft pipeline run.
+type reportResult struct {
+	Markdown string
+	Findings Findings
+	Fallback bool // OpenAI drafting failed or was skipped; Markdown is buildFallbackMarkdown output
+}
+
+// runReport runs the searches for spec, tags new hits, and drafts the Markdown report.
+// It is shared by the web UI and the headless `run` command. setStatus may be nil.
+// On error the result still holds whatever findings were collected.
+func runReport(parent context.Context, cfg AppSettings, spec *QueriesSpec, runID string, emit func(DebugEvent), setStatus func(string)) (reportResult, error) {
+	if setStatus == nil {
+		setStatus = func(string) {}
+	}
+	started := time.Now()
 
 	// Compute an adaptive timeout based on how many searches you'll make.
 	// Roughly 2.2s/request + margin. Floor 2m, cap 6m.
@@ -513,80 +1164,122 @@ func (s *Server) handleRunReport(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 	perReq := 12000 * time.Millisecond
-	budget := time.Duration(totalSearches*max(1, s.cfg.MaxPages))*perReq + 60*time.Second
+	if srcs, err := defaultTokenSources(); err == nil && len(srcs) > 1 {
+		perReq /= time.Duration(len(srcs)) // searches rotate across the token pool
+	}
+	budget := time.Duration(totalSearches*max(1, cfg.MaxPages))*perReq + 60*time.Second
 	if budget < 4*time.Minute { budget = 4*time.Minute }
 	if budget > 10*time.Minute { budget = 10*time.Minute }
-	ctx, cancel := context.WithTimeout(r.Context(), budget)
+	ctx, searchBudget, cancel := withRunBudget(parent, budget, perReq)
 	defer cancel()
 	emit(DebugEvent{Phase: "start", Note: fmt.Sprintf("budget=%s daysBack=%d maxPages=%d perPage=%d includeRepo=%v commitCheck=%v",
-		budget, s.cfg.DaysBack, s.cfg.MaxPages, s.cfg.PerPage, s.cfg.IncludeRepoSearch, s.cfg.UseCommitCheck)})
-
-	// mark progress and expose via /api/status
-	s.mu.Lock()
-	s.inProgress = true
-	s.status = "Running GitHub searches..."
-	s.mu.Unlock()
-	defer func(){
-		s.mu.Lock()
-		s.inProgress = false
-		s.status = "Done."
-		s.mu.Unlock()
-	}()
+		budget, cfg.DaysBack, cfg.MaxPages, cfg.PerPage, cfg.IncludeRepoSearch, cfg.UseCommitCheck)})
 
-	findings, err := runSearches(ctx, s.cfg, spec, emit)
+	setStatus("Running GitHub searches...")
+	findings, err := runSearches(ctx, cfg, spec, searchBudget, emit)
+	findings.RunID = runID
 	if err != nil {
-		emit(DebugEvent{Phase: "error", Note: "search phase: " + err.Error()})
-		http.Error(w, "search error: "+err.Error(), 500)
-		return
+		if parent.Err() != nil && errors.Is(err, context.Canceled) {
+			emit(DebugEvent{Phase: "cancelled", Note: fmt.Sprintf("during searches; partial codeHits=%d repoHits=%d commitHits=%d issueHits=%d", len(findings.CodeHits), len(findings.RepoHits), len(findings.CommitHits), len(findings.IssueHits))})
+			findings.Notes = append(findings.Notes, "Run cancelled during searches; findings are partial")
+			// tag new hits so the partial report counts them; nothing is recorded as seen
+			if err := markSeen(cfg.SeenFile, &findings); err != nil {
+				emit(DebugEvent{Phase: "seen-error", Note: err.Error()})
+				findings.Notes = append(findings.Notes, "seen store: "+err.Error())
+			}
+			return reportResult{Findings: findings, Markdown: buildFallbackMarkdown(findings, errRunCancelled), Fallback: true}, errRunCancelled
+		}
+		if context.Cause(ctx) == context.DeadlineExceeded {
+			// out of time, not broken: report what was found
+			emit(DebugEvent{Phase: "budget-exhausted", Note: fmt.Sprintf("during searches; partial codeHits=%d repoHits=%d commitHits=%d issueHits=%d", len(findings.CodeHits), len(findings.RepoHits), len(findings.CommitHits), len(findings.IssueHits))})
+			findings.Notes = append(findings.Notes, "Search time budget ran out; findings are partial")
+			err = nil
+		} else {
+			emit(DebugEvent{Phase: "error", Note: "search phase: " + err.Error()})
+			return reportResult{Findings: findings}, err
+		}
+	}
+	emit(DebugEvent{Phase: "search-summary", Note: fmt.Sprintf("codeHits=%d repoHits=%d commitHits=%d issueHits=%d notes=%d", len(findings.CodeHits), len(findings.RepoHits), len(findings.CommitHits), len(findings.IssueHits), len(findings.Notes))})
+
+	// Tag hits as new/returning against the persistent seen store; they are recorded
+	// as seen only once a report about them has been produced
+	seenOK := true
+	if err := markSeen(cfg.SeenFile, &findings); err != nil {
+		seenOK = false
+		emit(DebugEvent{Phase: "seen-error", Note: err.Error()})
+		findings.Notes = append(findings.Notes, "seen store: "+err.Error())
+	}
+	recordSeenHits := func() {
+		if !seenOK {
+			return
+		}
+		if err := recordSeen(cfg.SeenFile, findings); err != nil {
+			emit(DebugEvent{Phase: "seen-error", Note: err.Error()})
+		}
+	}
+	nc := countNew(findings)
+	emit(DebugEvent{Phase: "seen", Note: fmt.Sprintf("newCode=%d newRepo=%d newCommit=%d newIssue=%d", nc.Code, nc.Repo, nc.Commit, nc.Issue)})
+	reportF := findings
+	if cfg.NewOnly {
+		reportF = onlyNew(findings)
+	}
+
+	res := reportResult{Findings: findings}
+	if cfg.SkipOpenAI {
+		emit(DebugEvent{Phase: "openai-skipped", Note: "drafting disabled; using fallback"})
+		res.Markdown = buildFallbackMarkdown(reportF, nil) + changesSince(cfg, findings, started, emit)
+		res.Fallback = true
+		recordSeenHits()
+		emit(DebugEvent{Phase: "done", Note: fmt.Sprintf("markdownLen=%d", len(res.Markdown))})
+		return res, nil
 	}
-	findings.RunID = runID
-	emit(DebugEvent{Phase: "search-summary", Note: fmt.Sprintf("codeHits=%d repoHits=%d notes=%d", len(findings.CodeHits), len(findings.RepoHits), len(findings.Notes))})
 
 	// next phase
-	s.mu.Lock(); s.status = "Drafting report with OpenAI..."; s.mu.Unlock()
+	setStatus("Drafting report with OpenAI...")
 	openAITimeout := 10 * time.Minute
-	emit(DebugEvent{Phase: "openai", Note: fmt.Sprintf("model=%s payload=compact timeout=%s", s.cfg.OpenAIModel, openAITimeout)})
-	openCtx, openCancel := context.WithTimeout(context.Background(), openAITimeout)
+	emit(DebugEvent{Phase: "openai", Note: fmt.Sprintf("model=%s payload=compact timeout=%s", cfg.OpenAIModel, openAITimeout)})
+	openCtx, openCancel := context.WithTimeout(parent, openAITimeout)
 	defer openCancel()
-	md, err := draftReportWithOpenAI(openCtx, s.cfg, findings)
+	md, err := draftReportWithOpenAI(openCtx, cfg, reportF)
+	if err != nil && parent.Err() != nil {
+		emit(DebugEvent{Phase: "cancelled", Note: "during OpenAI drafting; findings complete"})
+		res.Markdown = buildFallbackMarkdown(reportF, errRunCancelled)
+		res.Fallback = true
+		return res, errRunCancelled
+	}
 	if err != nil {
 		// Fallback: return a minimal markdown report so the UI still shows something
-		s.mu.Lock(); s.status = "OpenAI failed; returning fallback report."; s.mu.Unlock()
+		setStatus("OpenAI failed; returning fallback report.")
 		emit(DebugEvent{Phase: "openai-error", Note: err.Error()})
-		md = buildFallbackMarkdown(findings, err)
+		md = buildFallbackMarkdown(reportF, err)
+		res.Fallback = true
 	}
 	if strings.TrimSpace(md) == "" {
 		emit(DebugEvent{Phase: "openai-empty", Note: "empty content from OpenAI; using fallback"})
-		md = buildFallbackMarkdown(findings, errors.New("empty OpenAI response"))
+		md = buildFallbackMarkdown(reportF, errors.New("empty OpenAI response"))
+		res.Fallback = true
+	}
+	if res.Fallback {
+		// drafting failed: the next run reports these hits as new again
+		emit(DebugEvent{Phase: "seen-skipped", Note: "no drafted report; hits not recorded as seen"})
+	} else {
+		recordSeenHits()
 	}
+	md = strings.TrimRight(md, "\n") + "\n\n" + changesSince(cfg, findings, started, emit)
 	emit(DebugEvent{Phase: "done", Note: fmt.Sprintf("markdownLen=%d", len(md))})
-
-	s.mu.Lock()
-	s.markdown = md
-	s.raw = findings
-	s.mu.Unlock()
-
-	writeJSON(w, map[string]any{"markdown": md})
+	res.Markdown = md
+	return res, nil
 }
 
-func (s *Server) handleRuns(w http.ResponseWriter, r *http.Request) {
-    s.runsMu.RLock()
-    ids := make([]string, 0, len(s.runs))
-    for id := range s.runs { ids = append(ids, id) }
-    s.runsMu.RUnlock()
-    sort.Strings(ids)
-    writeJSON(w, map[string]any{"runs": ids, "last": s.lastRunID})
-}
-
-func (s *Server) handleDebug(w http.ResponseWriter, r *http.Request) {
-    run := r.URL.Query().Get("run")
-    if run == "" || run == "last" {
-        run = s.lastRunID
-    }
-    s.runsMu.RLock()
-    evs := append([]DebugEvent(nil), s.runs[run]...)
-    s.runsMu.RUnlock()
-    writeJSON(w, map[string]any{"runId": run, "events": evs})
+// changesSince renders the "Changes since last run" section for f, or "" when
+// there is no earlier completed run to compare with.
+func changesSince(cfg AppSettings, f Findings, started time.Time, emit func(DebugEvent)) string {
+	sec, err := changesSection(cfg.RunsDir, f, started)
+	if err != nil {
+		emit(DebugEvent{Phase: "diff-error", Note: err.Error()})
+		return ""
+	}
+	return sec
 }
 
 // ====== Queries loader ======
@@ -604,39 +1297,81 @@ func loadQueries(path string) (*QueriesSpec, error) {
 	if len(q.Groups) == 0 {
 		return nil, errors.New("no groups in queries.yaml")
 	}
+	// hits, per-group exclude rules and clients are looked up by group name, so a
+	// second group with the same name would silently take over the first one's
+	names := map[string]bool{}
+	for _, g := range q.Groups {
+		if g.Name != "" && names[g.Name] {
+			return nil, fmt.Errorf("duplicate group name %q", g.Name)
+		}
+		names[g.Name] = true
+	}
 	return &q, nil
 }
 
-// ====== GitHub client & search ======
-
-type ghClient struct {
-	token string
-}
-
-func newGH() *ghClient {
-	return &ghClient{token: os.Getenv("GITHUB_TOKEN")}
-}
-
-func (c *ghClient) get(ctx context.Context, url string) (*http.Response, error) {
-	req, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
-	req.Header.Set("Accept", "application/vnd.github+json")
-	if c.token != "" {
-		req.Header.Set("Authorization", "Bearer "+c.token)
+// knownSearchTypes lists the SearchQuery.Type values runSearches understands.
+var knownSearchTypes = map[string]bool{"code": true, "repo": true, "commits": true, "issues": true, "pulls": true}
+
+// validateQueries reports problems that would make searches fail or be skipped at run time.
+func validateQueries(spec *QueriesSpec) []string {
+	var problems []string
+	enabled := 0
+	for gi, g := range spec.Groups {
+		gName := g.Name
+		if strings.TrimSpace(gName) == "" {
+			gName = fmt.Sprintf("group #%d", gi+1)
+			problems = append(problems, gName+": missing name")
+		}
+		if len(g.Searches) == 0 {
+			problems = append(problems, gName+": no searches")
+		}
+		for qi, q := range g.Searches {
+			qName := q.Name
+			if strings.TrimSpace(qName) == "" {
+				qName = fmt.Sprintf("search #%d", qi+1)
+			}
+			if !knownSearchTypes[strings.ToLower(q.Type)] {
+				problems = append(problems, fmt.Sprintf("%s — %s: unknown type %q", gName, qName, q.Type))
+			}
+			if strings.TrimSpace(q.Query) == "" {
+				problems = append(problems, fmt.Sprintf("%s — %s: empty query", gName, qName))
+			}
+			if g.Enabled && q.Enabled {
+				enabled++
+			}
+		}
 	}
-	return http.DefaultClient.Do(req)
+	if enabled == 0 {
+		problems = append(problems, "no enabled searches")
+	}
+	if _, err := newExclusions(spec); err != nil {
+		problems = append(problems, err.Error())
+	}
+	for _, g := range spec.Groups {
+		if env := strings.TrimSpace(g.TokenEnv); g.Enabled && env != "" && os.Getenv(env) == "" {
+			problems = append(problems, fmt.Sprintf("%s: tokenEnv %s is not set", g.Name, env))
+		}
+		if b := strings.TrimSpace(g.APIBase); b != "" && !strings.HasPrefix(b, "https://") && !strings.HasPrefix(b, "http://") {
+			problems = append(problems, fmt.Sprintf("%s: apiBase %q is not an http(s) URL", g.Name, b))
+		}
+	}
+	return problems
 }
 
+// ====== GitHub client & search ======
+
 type codeSearchResp struct {
 	TotalCount        int           `json:"total_count"`
 	IncompleteResults bool          `json:"incomplete_results"`
 	Items             []codeItem    `json:"items"`
 }
 type codeItem struct {
-	Name       string     `json:"name"`
-	Path       string     `json:"path"`
-	SHA        string     `json:"sha"`
-	HTMLURL    string     `json:"html_url"`
-	Repository codeRepo   `json:"repository"`
+	Name        string      `json:"name"`
+	Path        string      `json:"path"`
+	SHA         string      `json:"sha"`
+	HTMLURL     string      `json:"html_url"`
+	Repository  codeRepo    `json:"repository"`
+	TextMatches []textMatch `json:"text_matches"`
 }
 type codeRepo struct {
 	FullName string `json:"full_name"`
@@ -656,6 +1391,9 @@ type repoItem struct {
 	PushedAt    string   `json:"pushed_at"`
 	CreatedAt   string   `json:"created_at"`
 	Topics      []string `json:"topics"`
+	Stars       int      `json:"stargazers_count"`
+	Archived    bool     `json:"archived"`
+	Fork        bool     `json:"fork"`
 }
 
 type commitResp []struct {
@@ -668,24 +1406,85 @@ type commitResp []struct {
 	HTMLURL string `json:"html_url"`
 }
 
-func runSearches(ctx context.Context, cfg AppSettings, spec *QueriesSpec, emit func(DebugEvent)) (Findings, error) {
+// runSearches runs every enabled search; budget (may be nil) is extended for the
+// requests window splits add.
+func runSearches(ctx context.Context, cfg AppSettings, spec *QueriesSpec, budget *runBudget, emit func(DebugEvent)) (Findings, error) {
 	since := time.Now().Add(-time.Duration(cfg.DaysBack) * 24 * time.Hour).UTC()
 	sinceISO := since.Format(time.RFC3339)
 
-	client := newGH()
+	runClient, err := newGH(cfg, emit)
+	if err != nil {
+		return Findings{SinceISO: sinceISO, DaysBack: cfg.DaysBack, Generated: time.Now().Format(time.RFC3339)}, err
+	}
+	runClient.budget = budget
+	if n, err := runClient.cache.prune(cacheMaxAge); err != nil {
+		emit(DebugEvent{Phase: "cache-error", Note: err.Error()})
+	} else if n > 0 {
+		emit(DebugEvent{Phase: "cache-prune", Note: fmt.Sprintf("removed %d entries unused for %d days", n, int(cacheMaxAge.Hours()/24))})
+	}
+	excl, err := newExclusions(spec)
+	if err != nil {
+		return Findings{SinceISO: sinceISO, DaysBack: cfg.DaysBack, Generated: time.Now().Format(time.RFC3339)}, err
+	}
+	groupClients := map[string]*ghClient{} // by group name, for the commit check
+	defer func() {
+		emit(DebugEvent{Phase: "token-usage", Note: runClient.usage.String()})
+	}()
 	var codeHits []CodeHit
 	var repoHits []RepoHit
+	var commitHits []CommitHit
+	var issueHits []IssueHit
 	var notes []string
 
 	perPage := cfg.PerPage
 	maxPages := cfg.MaxPages
 
-	// Rate safety handled by throttleFrom()
+	// mergeAndExclude merges duplicate hits, then applies the exclusion rules to the
+	// merged ones. Excluded hits are removed, so running it again never counts them twice.
+	mergeAndExclude := func() {
+		codeHits, repoHits = excl.code(dedupeCode(codeHits)), excl.repos(dedupeRepo(repoHits))
+		commitHits, issueHits = excl.commits(dedupeCommit(commitHits)), excl.issues(dedupeIssue(issueHits))
+	}
+
+	// partial packages whatever was collected so far; returned alongside errors so
+	// a cancelled or failed run still keeps its findings.
+	partial := func() Findings {
+		mergeAndExclude()
+		f := Findings{
+			SinceISO:   sinceISO,
+			DaysBack:   cfg.DaysBack,
+			Generated:  time.Now().Format(time.RFC3339),
+			CodeHits:   codeHits,
+			RepoHits:   repoHits,
+			CommitHits: commitHits,
+			IssueHits:  issueHits,
+			Notes:      append(append([]string(nil), notes...), excl.notes()...),
+		}
+		if runClient.cache.hits.Load()+runClient.cache.misses.Load() > 0 {
+			f.Notes = append(f.Notes, runClient.cache.String())
+		}
+		rankFindings(&f, cfg.Scoring, since)
+		return f
+	}
+
+	// Rate safety handled by client.limiter
+
+	// skipped notes a query given up on after transient errors, so the others still run;
+	// any other error ends the search phase.
+	skipped := func(qName string, err error) bool {
+		if !errors.Is(err, errGaveUp) || ctx.Err() != nil {
+			return false
+		}
+		notes = append(notes, fmt.Sprintf("(%s) skipped: %v", qName, err))
+		return true
+	}
 
 	for _, g := range spec.Groups {
 		if !g.Enabled {
 			continue
 		}
+		client := runClient.forGroup(g)
+		groupClients[g.Name] = client
 		for _, q := range g.Searches {
 			if !q.Enabled {
 				continue
@@ -698,17 +1497,19 @@ func runSearches(ctx context.Context, cfg AppSettings, spec *QueriesSpec, emit f
 				for page <= maxPages {
 					select {
 					case <-ctx.Done():
-						return Findings{}, ctx.Err()
+						return partial(), ctx.Err()
 					default:
 					}
 					rawQ := sanitizeCodeQuery(q.Query)
-					url := fmt.Sprintf("https://api.github.com/search/code?q=%s&sort=indexed&order=desc&per_page=%d&page=%d",
-						urlQueryEscape(rawQ), perPage, page)
+					url := client.searchURL("code", urlQueryEscape(rawQ), "indexed", perPage, page)
 					emit(DebugEvent{Phase: "search-code", Group: g.Name, QueryName: q.Name, URL: url, Page: page})
-					resp, err := client.get(ctx, url)
+					resp, err := client.getTextMatch(ctx, url)
 					if err != nil {
 						emit(DebugEvent{Phase: "search-code-error", Group: g.Name, QueryName: q.Name, URL: url, Page: page, Note: err.Error()})
-						return Findings{}, err
+						if skipped(qName, err) {
+							break
+						}
+						return partial(), err
 					}
 					body, _ := io.ReadAll(resp.Body)
 					_ = resp.Body.Close()
@@ -726,38 +1527,27 @@ func runSearches(ctx context.Context, cfg AppSettings, spec *QueriesSpec, emit f
 						emit(DebugEvent{Phase: "search-code-non200", Group: g.Name, QueryName: q.Name, URL: url, Page: page, Status: resp.StatusCode, RateRemaining: rlRem, RateReset: rlRes, Note: note})
 						// If GitHub says the query cannot be parsed, retry once with strict escaping
 						if resp.StatusCode == 422 {
-							strictURL := fmt.Sprintf("https://api.github.com/search/code?q=%s&sort=indexed&order=desc&per_page=%d&page=%d",
-								neturl.QueryEscape(strings.TrimSpace(rawQ)), perPage, page)
+							strictURL := client.searchURL("code", neturl.QueryEscape(strings.TrimSpace(rawQ)), "indexed", perPage, page)
 							emit(DebugEvent{Phase: "search-code-retry", Group: g.Name, QueryName: q.Name, URL: strictURL, Page: page, Note: "retry with QueryEscape due to 422"})
-							resp2, err2 := client.get(ctx, strictURL)
+							resp2, err2 := client.getTextMatch(ctx, strictURL)
 							if err2 == nil {
 								body2, _ := io.ReadAll(resp2.Body)
 								_ = resp2.Body.Close()
 								if resp2.StatusCode == 200 {
 									var cr2 codeSearchResp
 									if err := json.Unmarshal(body2, &cr2); err != nil {
-										return Findings{}, err
+										return partial(), err
 									}
 									if len(cr2.Items) == 0 {
 										emit(DebugEvent{Phase: "search-code-ok", Group: g.Name, QueryName: q.Name, URL: strictURL, Page: page, Status: 200, Note: "0 items"})
 										break
 									}
 									for _, it := range cr2.Items {
-										hit := CodeHit{
-											Group:      g.Name,
-											QueryName:  q.Name,
-											Repository: it.Repository.FullName,
-											RepoURL:    it.Repository.HTMLURL,
-											FilePath:   it.Path,
-											FileURL:    it.HTMLURL,
-											Language:   it.Repository.Language,
-										}
-										codeHits = append(codeHits, hit)
+										codeHits = append(codeHits, codeHitFrom(it, g, q))
 										foundThisQuery++
 									}
 									emit(DebugEvent{Phase: "search-code-ok", Group: g.Name, QueryName: q.Name, URL: strictURL, Page: page, Status: 200, Note: fmt.Sprintf("items=%d", len(cr2.Items))})
 									page++
-									throttleFrom(resp2)
 									continue
 								}
 								// annotate second failure
@@ -768,33 +1558,22 @@ func runSearches(ctx context.Context, cfg AppSettings, spec *QueriesSpec, emit f
 						}
 						notes = append(notes, fmt.Sprintf("(%s) status=%d remaining=%s reset=%s url=%s body=%s",
 							qName, resp.StatusCode, rlRem, rlRes, url, truncate(string(body), 400)))
-						throttleFrom(resp)
 						break
 					}
 					var cr codeSearchResp
 					if err := json.Unmarshal(body, &cr); err != nil {
-						return Findings{}, err
+						return partial(), err
 					}
 					if len(cr.Items) == 0 {
 						emit(DebugEvent{Phase: "search-code-ok", Group: g.Name, QueryName: q.Name, URL: url, Page: page, Status: 200, Note: "0 items"})
 						break
 					}
 					for _, it := range cr.Items {
-						hit := CodeHit{
-							Group:      g.Name,
-							QueryName:  q.Name,
-							Repository: it.Repository.FullName,
-							RepoURL:    it.Repository.HTMLURL,
-							FilePath:   it.Path,
-							FileURL:    it.HTMLURL,
-							Language:   it.Repository.Language,
-						}
-						codeHits = append(codeHits, hit)
+						codeHits = append(codeHits, codeHitFrom(it, g, q))
 						foundThisQuery++
 					}
 					emit(DebugEvent{Phase: "search-code-ok", Group: g.Name, QueryName: q.Name, URL: url, Page: page, Status: 200, Note: fmt.Sprintf("items=%d", len(cr.Items))})
 					page++
-					throttleFrom(resp)
 				}
 				if foundThisQuery == 0 {
 					notes = append(notes, fmt.Sprintf("No code hits returned for %s", qName))
@@ -804,74 +1583,25 @@ func runSearches(ctx context.Context, cfg AppSettings, spec *QueriesSpec, emit f
 				if !cfg.IncludeRepoSearch {
 					continue
 				}
-				page := 1
-				foundThisQuery := 0
-				// Automatically add pushed:>= filter for recency window
-				baseQ := fmt.Sprintf("%s pushed:>=%s", q.Query, since.Format("2006-01-02"))
-				for page <= maxPages {
-					select {
-					case <-ctx.Done():
-						return Findings{}, ctx.Err()
-					default:
-					}
-					url := fmt.Sprintf("https://api.github.com/search/repositories?q=%s&sort=updated&order=desc&per_page=%d&page=%d",
-						urlQueryEscape(baseQ), perPage, page)
-					emit(DebugEvent{Phase: "search-repo", Group: g.Name, QueryName: q.Name, URL: url, Page: page})
-					resp, err := client.get(ctx, url)
-					if err != nil {
-						emit(DebugEvent{Phase: "search-repo-error", Group: g.Name, QueryName: q.Name, URL: url, Page: page, Note: err.Error()})
-						return Findings{}, err
-					}
-					body, _ := io.ReadAll(resp.Body)
-					_ = resp.Body.Close()
-					if resp.StatusCode != 200 {
-						rlRem := resp.Header.Get("X-RateLimit-Remaining")
-						rlRes := resp.Header.Get("X-RateLimit-Reset")
-						note := truncate(string(body), 200)
-						if resp.StatusCode == 403 || rlRem == "0" {
-							if ru, err := strconv.ParseInt(rlRes, 10, 64); err == nil {
-								ws := int(time.Until(time.Unix(ru, 0)).Seconds())
-								if ws > 0 { note = fmt.Sprintf("rate-limited; sleeping %ds; body=%s", ws, note) }
-							}
-						}
-						notes = append(notes, fmt.Sprintf("(%s) status=%d remaining=%s reset=%s url=%s body=%s",
-							qName, resp.StatusCode, rlRem, rlRes, url, truncate(string(body), 400)))
-						emit(DebugEvent{Phase: "search-repo-non200", Group: g.Name, QueryName: q.Name, URL: url, Page: page, Status: resp.StatusCode, RateRemaining: rlRem, RateReset: rlRes, Note: note})
-						throttleFrom(resp)
-						break
-					}
-					var rr repoSearchResp
-					if err := json.Unmarshal(body, &rr); err != nil {
-						return Findings{}, err
-					}
-					if len(rr.Items) == 0 {
-						emit(DebugEvent{Phase: "search-repo-ok", Group: g.Name, QueryName: q.Name, URL: url, Page: page, Status: 200, Note: "0 items"})
-						break
-					}
-					for _, it := range rr.Items {
-						pushed, _ := time.Parse(time.RFC3339, it.PushedAt)
-						created, _ := time.Parse(time.RFC3339, it.CreatedAt)
-						if pushed.Before(since) {
-							continue
-						}
-						repoHits = append(repoHits, RepoHit{
-							Group:       g.Name,
-							QueryName:   q.Name,
-							FullName:    it.FullName,
-							HTMLURL:     it.HTMLURL,
-							Description: it.Description,
-							PushedAt:    pushed,
-							CreatedAt:   created,
-						})
-						foundThisQuery++
-					}
-					emit(DebugEvent{Phase: "search-repo-ok", Group: g.Name, QueryName: q.Name, URL: url, Page: page, Status: 200, Note: fmt.Sprintf("items=%d", len(rr.Items))})
-					page++
-					throttleFrom(resp)
+				hits, qNotes, err := searchRepos(ctx, client, cfg, g, q, since, emit)
+				repoHits = append(repoHits, hits...)
+				notes = append(notes, qNotes...)
+				if err != nil && !skipped(qName, err) {
+					return partial(), err
 				}
-				if foundThisQuery == 0 {
-					notes = append(notes, fmt.Sprintf("No repo hits for %s", qName))
-					emit(DebugEvent{Phase: "search-repo-empty", Group: g.Name, QueryName: q.Name, Note: "no repo hits"})
+			case "commits":
+				hits, qNotes, err := searchCommits(ctx, client, cfg, g, q, since, emit)
+				commitHits = append(commitHits, hits...)
+				notes = append(notes, qNotes...)
+				if err != nil && !skipped(qName, err) {
+					return partial(), err
+				}
+			case "issues", "pulls":
+				hits, qNotes, err := searchIssues(ctx, client, cfg, g, q, strings.ToLower(q.Type), since, emit)
+				issueHits = append(issueHits, hits...)
+				notes = append(notes, qNotes...)
+				if err != nil && !skipped(qName, err) {
+					return partial(), err
 				}
 			default:
 				notes = append(notes, fmt.Sprintf("Unknown type for %s: %s", qName, q.Type))
@@ -880,13 +1610,36 @@ func runSearches(ctx context.Context, cfg AppSettings, spec *QueriesSpec, emit f
 		}
 	}
 
+	// Merge and exclude before the lookups below so they skip duplicates and dropped
+	// hits; code-hit stars, archived and fork rules wait for repo metadata (partial
+	// applies them)
+	mergeAndExclude()
+
+	// clientFor maps a code hit back to its group's host and token
+	clientFor := func(h CodeHit) *ghClient {
+		if c, ok := groupClients[h.Group]; ok {
+			return c
+		}
+		return runClient
+	}
+
 	// Optional: verify code file recency by hitting commits endpoint for each file
 	if cfg.UseCommitCheck && len(codeHits) > 0 {
-		emit(DebugEvent{Phase: "commit-check", Note: fmt.Sprintf("files=%d", len(codeHits))})
-		codeHits = enrichWithCommitDates(ctx, client, since, codeHits)
+		emit(DebugEvent{Phase: "commit-check", Note: fmt.Sprintf("files=%d mode=%s", len(codeHits), cfg.CommitCheckMode)})
+		var verified []CodeHit
+		if cfg.CommitCheckMode == commitCheckGraphQL {
+			verified = enrichWithCommitDatesGraphQL(ctx, clientFor, since, codeHits, emit)
+		} else {
+			verified = enrichWithCommitDates(ctx, clientFor, since, codeHits)
+		}
+		if err := ctx.Err(); err != nil {
+			// interrupted mid-check: keep the hits, unverified, rather than dropping them all
+			notes = append(notes, "Commit check interrupted; code hits are unverified")
+			return partial(), err
+		}
 		// keep only those with commitDate >= since; drop unverified
-		out := codeHits[:0]
-		for _, h := range codeHits {
+		out := verified[:0]
+		for _, h := range verified {
 			if h.CommitDate.IsZero() {
 				continue
 			}
@@ -898,33 +1651,64 @@ func runSearches(ctx context.Context, cfg AppSettings, spec *QueriesSpec, emit f
 		emit(DebugEvent{Phase: "commit-check-done", Note: fmt.Sprintf("kept=%d", len(codeHits))})
 	}
 
-	// Ensure deterministic ordering by recency
-	if len(codeHits) > 1 {
-		sort.Slice(codeHits, func(i, j int) bool {
-			ai := codeHits[i].CommitDate
-			aj := codeHits[j].CommitDate
-			if ai.IsZero() && aj.IsZero() { return false }
-			if ai.IsZero() { return false }
-			if aj.IsZero() { return true }
-			return ai.After(aj)
-		})
+	// Optional: stars, license, topics etc. of each repository with code hits
+	if cfg.EnrichRepos && len(codeHits) > 0 {
+		codeHits = enrichWithRepoMeta(ctx, clientFor, codeHits, emit)
+		if err := ctx.Err(); err != nil {
+			notes = append(notes, "Repository metadata lookup interrupted; some code hits lack it")
+			return partial(), err
+		}
 	}
-	if len(repoHits) > 1 {
-		sort.Slice(repoHits, func(i, j int) bool { return repoHits[i].PushedAt.After(repoHits[j].PushedAt) })
+	if excl.needsMeta() && len(codeHits) > 0 && !cfg.EnrichRepos {
+		notes = append(notes, "minStars/archived/forks exclusions were not applied to code hits: repo metadata is off")
 	}
 
-	return Findings{
-		RunID:    "", // filled by caller
-		SinceISO:  sinceISO,
-		DaysBack:  cfg.DaysBack,
-		Generated: time.Now().Format(time.RFC3339),
-		CodeHits:  dedupeCode(codeHits),
-		RepoHits:  dedupeRepo(repoHits),
-		Notes:     notes,
-	}, nil
+	// partial applies the remaining (metadata) exclusions and ranks each list by
+	// relevance score (see scoring.go)
+	return partial(), nil // RunID filled by caller
 }
 
-func enrichWithCommitDates(ctx context.Context, c *ghClient, since time.Time, hits []CodeHit) []CodeHit {
+// searchRepos runs a repo query over pushed:-window slices; see windowedSearch.
+func searchRepos(ctx context.Context, client *ghClient, cfg AppSettings, g SearchGroup, q SearchQuery, since time.Time, emit func(DebugEvent)) ([]RepoHit, []string, error) {
+	var hits []RepoHit
+	ws := windowedSearch{kind: "repo", endpoint: "repositories", sort: "updated", field: "pushed", query: q.Query,
+		decode: func(body []byte) (int, bool, int, error) {
+			var rr repoSearchResp
+			if err := json.Unmarshal(body, &rr); err != nil {
+				return 0, false, 0, err
+			}
+			for _, it := range rr.Items {
+				pushed, _ := time.Parse(time.RFC3339, it.PushedAt)
+				created, _ := time.Parse(time.RFC3339, it.CreatedAt)
+				if pushed.Before(since) {
+					continue
+				}
+				hits = append(hits, RepoHit{
+					Group:       g.Name,
+					QueryName:   q.Name,
+					FullName:    it.FullName,
+					HTMLURL:     it.HTMLURL,
+					Description: it.Description,
+					PushedAt:    pushed,
+					CreatedAt:   created,
+					Stars:       it.Stars,
+					Archived:    it.Archived,
+					Fork:        it.Fork,
+				})
+			}
+			return rr.TotalCount, rr.IncompleteResults, len(rr.Items), nil
+		}}
+	notes, err := ws.run(ctx, client, cfg, g, q, since, emit)
+	if err == nil && len(hits) == 0 {
+		notes = append(notes, fmt.Sprintf("No repo hits for %s — %s", g.Name, q.Name))
+		emit(DebugEvent{Phase: "search-repo-empty", Group: g.Name, QueryName: q.Name, Note: "no repo hits"})
+	}
+	return hits, notes, err
+}
+
+// enrichWithCommitDates looks up each hit's latest commit since `since` on the host
+// clientFor picks for it.
+func enrichWithCommitDates(ctx context.Context, clientFor func(CodeHit) *ghClient, since time.Time, hits []CodeHit) []CodeHit {
 	type job struct{ i int; h CodeHit }
 	type res struct{ i int; t time.Time }
 
@@ -935,27 +1719,24 @@ func enrichWithCommitDates(ctx context.Context, c *ghClient, since time.Time, hi
 	worker := func() {
 		defer wg.Done()
 		for j := range jobs {
-			ownerRepo := j.h.Repository
-			path := j.h.FilePath
-			url := fmt.Sprintf("https://api.github.com/repos/%s/commits?path=%s&since=%s&per_page=1",
-				ownerRepo, neturl.PathEscape(path), since.Format(time.RFC3339))
-			reqCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
-			resp, err := c.get(reqCtx, url)
-			cancel()
+			c := clientFor(j.h)
+			url := c.commitsURL(j.h.Repository, j.h.FilePath, 1)
+			resp, err := c.get(ctx, url)
 			if err != nil {
 				results <- res{j.i, time.Time{}}
 				continue
 			}
 			body, _ := io.ReadAll(resp.Body)
 			_ = resp.Body.Close()
-			throttleFrom(resp)
 			var cr commitResp
 			if resp.StatusCode == 200 {
 				_ = json.Unmarshal(body, &cr)
 				if len(cr) > 0 {
-					d, _ := time.Parse(time.RFC3339, cr[0].Commit.Author.Date)
-					results <- res{j.i, d}
-					continue
+					// the latest commit; older than since means none in the window
+					if d, _ := time.Parse(time.RFC3339, cr[0].Commit.Author.Date); !d.Before(since) {
+						results <- res{j.i, d}
+						continue
+					}
 				}
 			}
 			results <- res{j.i, time.Time{}}
@@ -985,32 +1766,29 @@ func enrichWithCommitDates(ctx context.Context, c *ghClient, since time.Time, hi
 	return out
 }
 
+// dedupeCode merges hits on the same file; see mergeHits.
 func dedupeCode(in []CodeHit) []CodeHit {
-	seen := map[string]bool{}
-	out := make([]CodeHit, 0, len(in))
-	for _, h := range in {
-		key := h.Repository + "|" + h.FilePath + "|" + h.FileURL
-		if !seen[key] {
-			seen[key] = true
-			out = append(out, h)
-		}
-	}
-	return out
+	return mergeHits(in, codeKey, func(h *CodeHit) (*[]QueryRef, QueryRef) { return &h.MatchedBy, QueryRef{h.Group, h.QueryName} },
+		func(dst *CodeHit, src CodeHit) {
+			for _, s := range src.Snippets {
+				if len(dst.Snippets) < maxSnippetsPerHit && !hasSnippet(dst.Snippets, s) {
+					dst.Snippets = append(dst.Snippets, s)
+				}
+			}
+			if src.CommitDate.After(dst.CommitDate) {
+				dst.CommitDate = src.CommitDate
+			}
+		})
 }
 
 func dedupeRepo(in []RepoHit) []RepoHit {
-	seen := map[string]bool{}
-	out := make([]RepoHit, 0, len(in))
-	for _, h := range in {
-		key := h.FullName
-		if !seen[key] {
-			seen[key] = true
-			out = append(out, h)
-		}
-	}
-	return out
+	return mergeHits(in, repoKey, func(h *RepoHit) (*[]QueryRef, QueryRef) { return &h.MatchedBy, QueryRef{h.Group, h.QueryName} }, nil)
 }
 
+// codeKey and repoKey identify a hit across runs (dedupe + seen store).
+func codeKey(h CodeHit) string { return h.Repository + "|" + h.FilePath + "|" + h.FileURL }
+func repoKey(h RepoHit) string { return h.FullName }
+
 func urlQueryEscape(q string) string {
 	// Encode for query param but preserve GitHub search operators so semantics remain intact.
 	// Start with strict escaping, then unescape a safe subset used by GitHub search: :, (), >, <, =, ,, /, |
@@ -1044,51 +1822,16 @@ func sanitizeCodeQuery(q string) string {
 	return strings.TrimSpace(q)
 }
 
-func throttleFrom(resp *http.Response) {
-    resource := strings.ToLower(resp.Header.Get("X-RateLimit-Resource"))
-    var baseWait time.Duration
-    if resource == "search" {
-        baseWait = 12 * time.Second // ~5 req/min for search endpoints
-    } else {
-        baseWait = 1500 * time.Millisecond
-    }
-
-    rem, _ := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
-
-    // Honor Retry-After when sent (secondary rate limits)
-    if ra := resp.Header.Get("Retry-After"); ra != "" {
-        if secs, err := strconv.Atoi(strings.TrimSpace(ra)); err == nil && secs > 0 {
-            time.Sleep(time.Duration(secs)*time.Second + 500*time.Millisecond)
-            return
-        }
-    }
-
-    // If rate limited or nearly there, wait until reset (with caps)
-    if resp.StatusCode == 403 || rem <= 2 {
-        if resetUnix, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
-            wait := time.Until(time.Unix(resetUnix, 0))
-            if wait > 0 {
-                capWait := 2 * time.Minute
-                if resource != "search" {
-                    capWait = 5 * time.Minute
-                }
-                if wait > capWait { wait = capWait }
-                time.Sleep(wait + 500*time.Millisecond)
-                return
-            }
-        }
-        // Fallback conservative backoff if no usable reset
-        if resource == "search" {
-            time.Sleep(90 * time.Second)
-        } else {
-            time.Sleep(30 * time.Second)
-        }
-        return
-    }
-
-    // Normal gentle pacing + jitter
-    jitterMs := time.Now().UnixNano() % int64(2000*time.Millisecond)
-    time.Sleep(baseWait + time.Duration(jitterMs))
+// sleepCtx sleeps for d, or until ctx is done (returning ctx.Err()).
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
 }
 
 // ====== OpenAI drafting ======
@@ -1106,38 +1849,146 @@ func draftReportWithOpenAI(ctx context.Context, cfg AppSettings, f Findings) (st
 		Path string `json:"path"`
 		Lang string `json:"lang"`
 		Commit string `json:"commit,omitempty"`
+		Snippet string `json:"snippet,omitempty"` // first matched fragment, truncated
+		Score  float64  `json:"score"`
+		Matched string  `json:"matched,omitempty"` // "query A + query B" when several searches found it
+		Stars  int      `json:"stars,omitempty"`
+		Forks  int      `json:"forks,omitempty"`
+		License string  `json:"license,omitempty"`
+		Topics []string `json:"topics,omitempty"`
+		Owner  string   `json:"ownerType,omitempty"`
+		Archived bool   `json:"archived,omitempty"`
+		Fork   bool     `json:"fork,omitempty"`
+		Created string  `json:"repoCreated,omitempty"`
+		New  bool   `json:"new"`
+	}
+	type smallCommit struct {
+		Repo string `json:"repo"`
+		URL  string `json:"url"`
+		Msg  string `json:"msg"`
+		Date string `json:"date"`
+		New  bool   `json:"new"`
+	}
+	type smallIssue struct {
+		Kind     string   `json:"kind"`
+		Repo     string   `json:"repo"`
+		URL      string   `json:"url"`
+		Title    string   `json:"title"`
+		State    string   `json:"state"`
+		Labels   []string `json:"labels,omitempty"`
+		Comments int      `json:"comments"`
+		Updated  string   `json:"updated"`
+		New      bool     `json:"new"`
 	}
 	type smallRepo struct {
 		Full string `json:"full"`
 		URL  string `json:"url"`
 		Desc string `json:"desc,omitempty"`
 		Pushed string `json:"pushed"`
+		New  bool   `json:"new"`
 	}
 
-	codes := make([]smallCode, 0, min(200, len(f.CodeHits)))
-	for i, h := range f.CodeHits {
+	// Each list is ordered by (first-seen, score) before the 200-item cap, so the cap
+	// drops neither new hits nor the best-scoring returning ones.
+	newThenScore := func(newI, newJ bool, si, sj float64) bool {
+		if newI != newJ {
+			return newI
+		}
+		return si > sj
+	}
+	codeHits := append([]CodeHit(nil), f.CodeHits...)
+	sort.SliceStable(codeHits, func(i, j int) bool {
+		return newThenScore(codeHits[i].IsNew, codeHits[j].IsNew, codeHits[i].Score, codeHits[j].Score)
+	})
+	repoHits := append([]RepoHit(nil), f.RepoHits...)
+	sort.SliceStable(repoHits, func(i, j int) bool {
+		return newThenScore(repoHits[i].IsNew, repoHits[j].IsNew, repoHits[i].Score, repoHits[j].Score)
+	})
+
+	codes := make([]smallCode, 0, min(200, len(codeHits)))
+	for i, h := range codeHits {
 		if i >= 200 { break }
 		c := smallCode{
-			Repo: h.Repository, URL: h.FileURL, Path: h.FilePath, Lang: h.Language,
+			Repo: h.Repository, URL: h.FileURL, Path: h.FilePath, Lang: h.Language, New: h.IsNew, Score: h.Score,
+		}
+		if len(h.MatchedBy) > 1 {
+			c.Matched = matchedLabel(h.MatchedBy)
 		}
 		if !h.CommitDate.IsZero() {
 			c.Commit = h.CommitDate.Format("2006-01-02")
 		}
+		if len(h.Snippets) > 0 {
+			c.Snippet = truncate(h.Snippets[0].Fragment, llmSnippetLen)
+		}
+		if m := h.RepoMeta; m != nil {
+			c.Stars, c.Forks, c.License, c.Topics = m.Stars, m.Forks, m.License, m.Topics
+			c.Owner, c.Archived, c.Fork, c.Created = m.OwnerType, m.Archived, m.Fork, m.CreatedAt.Format("2006-01-02")
+		}
 		codes = append(codes, c)
 	}
-	repos := make([]smallRepo, 0, min(200, len(f.RepoHits)))
-	for i, h := range f.RepoHits {
+	commitHits := append([]CommitHit(nil), f.CommitHits...)
+	sort.SliceStable(commitHits, func(i, j int) bool {
+		return newThenScore(commitHits[i].IsNew, commitHits[j].IsNew, commitHits[i].Score, commitHits[j].Score)
+	})
+
+	repos := make([]smallRepo, 0, min(200, len(repoHits)))
+	for i, h := range repoHits {
 		if i >= 200 { break }
 		repos = append(repos, smallRepo{
-			Full: h.FullName, URL: h.HTMLURL, Desc: h.Description, Pushed: h.PushedAt.Format("2006-01-02"),
+			Full: h.FullName, URL: h.HTMLURL, Desc: h.Description, Pushed: h.PushedAt.Format("2006-01-02"), New: h.IsNew,
+		})
+	}
+
+	commits := make([]smallCommit, 0, min(200, len(commitHits)))
+	for i, h := range commitHits {
+		if i >= 200 { break }
+		commits = append(commits, smallCommit{
+			Repo: h.Repository, URL: h.CommitURL, Msg: h.Message, Date: h.CommittedAt.Format("2006-01-02"), New: h.IsNew,
 		})
 	}
 
+	issueHits := append([]IssueHit(nil), f.IssueHits...)
+	sort.SliceStable(issueHits, func(i, j int) bool {
+		return newThenScore(issueHits[i].IsNew, issueHits[j].IsNew, issueHits[i].Score, issueHits[j].Score)
+	})
+	issues := make([]smallIssue, 0, min(200, len(issueHits)))
+	for i, h := range issueHits {
+		if i >= 200 { break }
+		issues = append(issues, smallIssue{
+			Kind: h.Kind, Repo: h.Repository, URL: h.HTMLURL, Title: h.Title, State: h.State, Labels: h.Labels,
+			Comments: h.Comments, Updated: h.UpdatedAt.Format("2006-01-02"), New: h.IsNew,
+		})
+	}
+
+	type smallRepoAgg struct {
+		Repo   string   `json:"repo"`
+		URL    string   `json:"url"`
+		Files  int      `json:"files"`
+		New    int      `json:"newFiles,omitempty"`
+		Langs  []string `json:"langs,omitempty"`
+		Groups []string `json:"groups"`
+		Commit string   `json:"newestCommit,omitempty"`
+		Stars  int      `json:"stars,omitempty"`
+	}
+	aggs := aggregateByRepo(f.CodeHits)
+	codeRepos := make([]smallRepoAgg, 0, min(200, len(aggs)))
+	for i, a := range aggs {
+		if i >= 200 { break }
+		r := smallRepoAgg{Repo: a.Repository, URL: a.RepoURL, Files: a.Files, New: a.NewFiles, Langs: a.Languages, Groups: a.Groups, Stars: max(a.Stars, 0)}
+		if !a.NewestCommit.IsZero() {
+			r.Commit = a.NewestCommit.Format("2006-01-02")
+		}
+		codeRepos = append(codeRepos, r)
+	}
+
 	raw := map[string]any{
 		"since": f.SinceISO,
 		"daysBack": f.DaysBack,
+		"codeRepos": codeRepos,
 		"codeHits": codes,
 		"repoHits": repos,
+		"commitHits": commits,
+		"issueHits": issues,
 		"notes": f.Notes,
 	}
 	rawJSON, _ := json.Marshal(raw)
@@ -1145,7 +1996,16 @@ func draftReportWithOpenAI(ctx context.Context, cfg AppSettings, f Findings) (st
 	sys := "You are an assistant that writes concise, developer-friendly Markdown reports. " +
 		"Summarize GitHub search findings that touch market-data/broker APIs (Polygon.io, Alpaca, IBKR, Databento). " +
 		"Group by API when obvious (infer from URLs or package names), then list notable repos/files as bullet points with links. " +
-		"Prefer code hits over repo mentions. Include a short 'What to study' checklist (rate limiting, auth, streaming/REST). " +
+		"Prefer code hits over repo mentions. " +
+		"Present code hits per repository as a Markdown table built from \"codeRepos\" (repository, files, languages, groups, newest commit, stars) rather than one bullet per file; cite individual files from \"codeHits\" only for the most notable repositories. " +
+		"A code hit's \"snippet\" is the matching code; use it to say how the API is used (endpoint, client call, auth) rather than guessing from the path. " +
+		"Use stars, forks, ownerType and archived/fork flags, when present, to tell established projects from toys, and mention star counts for notable ones. " +
+		"Commit hits (messages like \"add alpaca integration\") are strong adoption signals; list them with their message. " +
+		"Issue/PR hits show developers asking about or contributing integrations; summarize the questions in a short section with links. " +
+		"Each list has new items first, then the rest by relevance (hits carry their \"score\"); keep that order within sections. " +
+		"A code hit's \"matched\" lists the searches that found it; mention it, since several independent matches are a stronger signal. " +
+		"Items with \"new\": true were first seen in this run; lead with those and mention returning items only briefly. " +
+		"Include a short 'What to study' checklist (rate limiting, auth, streaming/REST). " +
 		"Do not invent content; only use provided JSON. If there are zero results and no explicit error message in notes, say 'No results found in the selected window' and do not guess about parsing errors or rate limits."
 
 	usr := "Create a Markdown report for findings in the last " + strconv.Itoa(f.DaysBack) + " days.\n" +
@@ -1208,6 +2068,11 @@ func writeJSON(w http.ResponseWriter, v any) {
 	_ = enc.Encode(v)
 }
 
+func fmtTime(t time.Time) string {
+	if t.IsZero() { return "" }
+	return t.Format(time.RFC3339)
+}
+
 func truncate(s string, n int) string {
 	if len(s) <= n { return s }
 	return s[:n] + "…"
@@ -1216,7 +2081,9 @@ func truncate(s string, n int) string {
 func buildFallbackMarkdown(f Findings, err error) string {
 	var b strings.Builder
 	b.WriteString("# Report (fallback)\n\n")
-	if err != nil {
+	if errors.Is(err, errRunCancelled) {
+		b.WriteString("Run cancelled; the findings below may be partial.\n\n")
+	} else if err != nil {
 		b.WriteString("OpenAI drafting failed: ")
 		b.WriteString(err.Error())
 		b.WriteString("\n\n")
@@ -1231,7 +2098,23 @@ func buildFallbackMarkdown(f Findings, err error) string {
 	b.WriteString("\n")
 	b.WriteString("- Repo hits: ")
 	b.WriteString(strconv.Itoa(len(f.RepoHits)))
-	b.WriteString("\n\n")
+	b.WriteString("\n")
+	b.WriteString("- Commit hits: ")
+	b.WriteString(strconv.Itoa(len(f.CommitHits)))
+	b.WriteString("\n")
+	b.WriteString("- Issue/PR hits: ")
+	b.WriteString(strconv.Itoa(len(f.IssueHits)))
+	b.WriteString("\n")
+	nc := countNew(f)
+	b.WriteString("- New since previous runs: ")
+	b.WriteString(strconv.Itoa(nc.Code))
+	b.WriteString(" code, ")
+	b.WriteString(strconv.Itoa(nc.Repo))
+	b.WriteString(" repo, ")
+	b.WriteString(strconv.Itoa(nc.Commit))
+	b.WriteString(" commit, ")
+	b.WriteString(strconv.Itoa(nc.Issue))
+	b.WriteString(" issue/PR\n\n")
 	if len(f.Notes) > 0 {
 		b.WriteString("Notes:\n")
 		for _, n := range f.Notes {
@@ -1241,6 +2124,9 @@ func buildFallbackMarkdown(f Findings, err error) string {
 		}
 		b.WriteString("\n")
 	}
+	if len(f.CodeHits) > 0 {
+		writeRepoTable(&b, aggregateByRepo(f.CodeHits))
+	}
 	maxList := min(10, len(f.CodeHits))
 	if maxList > 0 {
 		b.WriteString("Top code hits:\n")
@@ -1250,7 +2136,13 @@ func buildFallbackMarkdown(f Findings, err error) string {
 			b.WriteString(h.Repository)
 			b.WriteString(": ")
 			b.WriteString(h.FileURL)
+			if h.RepoMeta != nil { b.WriteString(" (★ " + strconv.Itoa(h.RepoMeta.Stars) + ")") }
+			if len(h.MatchedBy) > 1 { b.WriteString(" — matched " + matchedLabel(h.MatchedBy)) }
+			if h.IsNew { b.WriteString(" (new)") }
 			b.WriteString("\n")
+			for _, sn := range h.Snippets {
+				writeSnippet(&b, sn, h.Language)
+			}
 		}
 		b.WriteString("\n")
 	}
@@ -1263,6 +2155,47 @@ func buildFallbackMarkdown(f Findings, err error) string {
 			b.WriteString(r.FullName)
 			b.WriteString(": ")
 			b.WriteString(r.HTMLURL)
+			if r.IsNew { b.WriteString(" (new)") }
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+	}
+	maxCommits := min(10, len(f.CommitHits))
+	if maxCommits > 0 {
+		b.WriteString("Top commits:\n")
+		for i := 0; i < maxCommits; i++ {
+			c := f.CommitHits[i]
+			b.WriteString("- ")
+			b.WriteString(c.Repository)
+			b.WriteString(": ")
+			b.WriteString(c.Message)
+			b.WriteString(" — ")
+			b.WriteString(c.CommitURL)
+			if c.IsNew { b.WriteString(" (new)") }
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+	}
+	maxIssues := min(10, len(f.IssueHits))
+	if maxIssues > 0 {
+		b.WriteString("Top issues/PRs:\n")
+		for i := 0; i < maxIssues; i++ {
+			h := f.IssueHits[i]
+			b.WriteString("- ")
+			b.WriteString(h.Repository)
+			b.WriteString(" #")
+			b.WriteString(strconv.Itoa(h.Number))
+			b.WriteString(" [")
+			b.WriteString(h.Kind)
+			b.WriteString(", ")
+			b.WriteString(h.State)
+			b.WriteString(", ")
+			b.WriteString(strconv.Itoa(h.Comments))
+			b.WriteString(" comments] ")
+			b.WriteString(h.Title)
+			b.WriteString(": ")
+			b.WriteString(h.HTMLURL)
+			if h.IsNew { b.WriteString(" (new)") }
 			b.WriteString("\n")
 		}
 		b.WriteString("\n")
@@ -1281,11 +2214,15 @@ func (s *Server) emitFunc(runID string) func(DebugEvent) {
 			s.runs[runID] = s.runs[runID][len(s.runs[runID])-1000:]
 		}
 		s.runsMu.Unlock()
-		log.Printf("[%s] %s %s %s (page=%d status=%d rl=%s rs=%s) %s",
-			ev.RunID, ev.TS, ev.Phase, ev.QueryName, ev.Page, ev.Status, ev.RateRemaining, ev.RateReset, ev.Note)
+		logEvent(ev)
 	}
 }
 
+func logEvent(ev DebugEvent) {
+	log.Printf("[%s] %s %s %s (page=%d status=%d rl=%s rs=%s) %s",
+		ev.RunID, ev.TS, ev.Phase, ev.QueryName, ev.Page, ev.Status, ev.RateRemaining, ev.RateReset, ev.Note)
+}
+
 func newRunID() string {
 	return time.Now().UTC().Format("20060102T150405Z")
 }
@@ -1296,6 +2233,13 @@ const defaultQueriesYAML = `# queries.yaml
 # You can edit this file from the UI. Toggle 'enabled' to include/exclude groups or searches.
 # Tip: keep queries tight and language-specific when possible; 'sort=indexed' is applied automatically for code.
 
+# Hits dropped after search, for every group (a group can add its own 'exclude:').
+# owners/repos are globs, paths are regexes; minStars, archived and forks need repo metadata for code hits.
+exclude:
+  owners: ["polygon-io", "alpacahq"]   # the vendors' own SDKs and examples
+  paths: ["(^|/)(node_modules|vendor)/"]
+  forks: true
+
 groups:
   - name: Polygon.io
     enabled: true
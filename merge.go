@@ -0,0 +1,78 @@
+// merge.go
+// Duplicate hits across queries are merged rather than dropped: the first hit for a key
+// stays, and every group/query that also found it is listed in its MatchedBy, so the
+// report can say "matched Polygon REST + Python usage" and scoring can reward it.
+
+package main
+
+import "strings"
+
+// QueryRef names a search that matched a hit.
+type QueryRef struct {
+	Group string `json:"group"`
+	Query string `json:"query"`
+}
+
+func (r QueryRef) String() string { return r.Group + " — " + r.Query }
+
+// addRefs appends the refs not yet in dst.
+func addRefs(dst []QueryRef, refs ...QueryRef) []QueryRef {
+	for _, r := range refs {
+		dup := false
+		for _, d := range dst {
+			if d == r {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			dst = append(dst, r)
+		}
+	}
+	return dst
+}
+
+// matchedLabel joins the searches behind a hit with " + ", naming only the query when
+// all come from one group.
+func matchedLabel(refs []QueryRef) string {
+	oneGroup := true
+	for _, r := range refs {
+		oneGroup = oneGroup && r.Group == refs[0].Group
+	}
+	parts := make([]string, len(refs))
+	for i, r := range refs {
+		if oneGroup {
+			parts[i] = r.Query
+		} else {
+			parts[i] = r.String()
+		}
+	}
+	return strings.Join(parts, " + ")
+}
+
+// mergeHits keeps the first hit per key, in order, and folds later ones into it with
+// merge. refs returns a hit's MatchedBy for filling in; a hit that has none yet counts
+// its own group and query.
+func mergeHits[T any](in []T, key func(T) string, refs func(*T) (*[]QueryRef, QueryRef), merge func(dst *T, src T)) []T {
+	idx := map[string]int{}
+	out := make([]T, 0, len(in))
+	for _, h := range in {
+		srcRefs, self := refs(&h)
+		matched := addRefs([]QueryRef{self}, *srcRefs...)
+		k := key(h)
+		i, ok := idx[k]
+		if !ok {
+			idx[k] = len(out)
+			*srcRefs = matched
+			out = append(out, h)
+			continue
+		}
+		dst := &out[i]
+		dstRefs, _ := refs(dst)
+		*dstRefs = addRefs(*dstRefs, matched...)
+		if merge != nil {
+			merge(dst, h)
+		}
+	}
+	return out
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMergeHits(t *testing.T) {
+	code := func(repo, path, group, query string, snippets ...string) CodeHit {
+		h := CodeHit{Group: group, QueryName: query, Repository: repo, FilePath: path, FileURL: "https://github.com/" + repo + "/blob/main/" + path}
+		for _, s := range snippets {
+			h.Snippets = append(h.Snippets, CodeSnippet{Fragment: s})
+		}
+		return h
+	}
+	ref := func(group, query string) QueryRef { return QueryRef{group, query} }
+	tests := []struct {
+		name     string
+		in       []CodeHit
+		paths    []string     // kept, in order
+		matched  [][]QueryRef // MatchedBy of each kept hit
+		snippets [][]string
+	}{
+		{
+			name:     "distinct",
+			in:       []CodeHit{code("a/b", "x.py", "G", "q1"), code("a/b", "y.py", "G", "q1")},
+			paths:    []string{"x.py", "y.py"},
+			matched:  [][]QueryRef{{ref("G", "q1")}, {ref("G", "q1")}},
+			snippets: [][]string{nil, nil},
+		},
+		{
+			name:     "same file, two queries",
+			in:       []CodeHit{code("a/b", "x.py", "G", "q1", "s1"), code("a/b", "y.py", "G", "q1"), code("a/b", "x.py", "H", "q2", "s2", "s1")},
+			paths:    []string{"x.py", "y.py"},
+			matched:  [][]QueryRef{{ref("G", "q1"), ref("H", "q2")}, {ref("G", "q1")}},
+			snippets: [][]string{{"s1", "s2"}, nil},
+		},
+		{
+			name:     "same query twice counts once",
+			in:       []CodeHit{code("a/b", "x.py", "G", "q1"), code("a/b", "x.py", "G", "q1")},
+			paths:    []string{"x.py"},
+			matched:  [][]QueryRef{{ref("G", "q1")}},
+			snippets: [][]string{nil},
+		},
+		{
+			name:     "snippets capped",
+			in:       []CodeHit{code("a/b", "x.py", "G", "q1", "s1", "s2"), code("a/b", "x.py", "G", "q2", "s3", "s4")},
+			paths:    []string{"x.py"},
+			matched:  [][]QueryRef{{ref("G", "q1"), ref("G", "q2")}},
+			snippets: [][]string{{"s1", "s2", "s3"}},
+		},
+	}
+	for _, tt := range tests {
+		out := dedupeCode(tt.in)
+		var paths []string
+		var matched [][]QueryRef
+		var snippets [][]string
+		for _, h := range out {
+			paths, matched = append(paths, h.FilePath), append(matched, h.MatchedBy)
+			var s []string
+			for _, sn := range h.Snippets {
+				s = append(s, sn.Fragment)
+			}
+			snippets = append(snippets, s)
+		}
+		if !reflect.DeepEqual(paths, tt.paths) || !reflect.DeepEqual(matched, tt.matched) || !reflect.DeepEqual(snippets, tt.snippets) {
+			t.Errorf("%s: got %v %v %v, want %v %v %v", tt.name, paths, matched, snippets, tt.paths, tt.matched, tt.snippets)
+		}
+		// merging merged hits changes nothing
+		if again := dedupeCode(append([]CodeHit(nil), out...)); !reflect.DeepEqual(again, out) {
+			t.Errorf("%s: second merge changed hits: %+v", tt.name, again)
+		}
+	}
+}
+
+func TestMergeHitsKeepsNewestCommit(t *testing.T) {
+	older := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := older.AddDate(0, 1, 0)
+	a := CodeHit{Group: "G", QueryName: "q1", Repository: "a/b", FilePath: "x.py", CommitDate: older}
+	b := a
+	b.QueryName, b.CommitDate = "q2", newer
+	if out := dedupeCode([]CodeHit{a, b}); len(out) != 1 || !out[0].CommitDate.Equal(newer) {
+		t.Errorf("merged = %+v, want one hit with commit %s", out, newer)
+	}
+}
+
+func TestMatchedLabel(t *testing.T) {
+	tests := []struct {
+		refs []QueryRef
+		want string
+	}{
+		{[]QueryRef{{"Polygon", "REST"}}, "REST"},
+		{[]QueryRef{{"Polygon", "REST"}, {"Polygon", "Python usage"}}, "REST + Python usage"},
+		{[]QueryRef{{"Polygon", "REST"}, {"Alpaca", "SDK"}}, "Polygon — REST + Alpaca — SDK"},
+	}
+	for _, tt := range tests {
+		if got := matchedLabel(tt.refs); got != tt.want {
+			t.Errorf("matchedLabel(%v) = %q, want %q", tt.refs, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+// ratelimit.go
+// Shared GitHub rate limiter: one token bucket per rate-limit resource (search,
+// code_search, core, graphql), re-synced from X-RateLimit-* headers after every response and
+// paused for secondary limits. Every ghClient for the same host and token shares one
+// limiter (see limiterFor), so overlapping runs and the commit-check workers draw from
+// the same budget; a client with several tokens has one limiter per token.
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rate-limit resources, as named by X-RateLimit-Resource.
+const (
+	resourceCore       = "core"
+	resourceSearch     = "search"
+	resourceCodeSearch = "code_search"
+	resourceGraphQL    = "graphql"
+)
+
+// bucketDefaults are the documented authenticated limits, used until headers arrive.
+var bucketDefaults = map[string]struct {
+	limit  int
+	window time.Duration
+}{
+	resourceCore:       {5000, time.Hour},
+	resourceSearch:     {30, time.Minute},
+	resourceCodeSearch: {10, time.Minute},
+	resourceGraphQL:    {5000, time.Hour}, // points, but a history batch costs about one
+}
+
+// secondaryLimitPause is how long to back off from a secondary limit without Retry-After.
+const secondaryLimitPause = time.Minute
+
+type rateBucket struct {
+	limit     int
+	window    time.Duration
+	tokens    float64 // may go negative: callers holding reservations
+	refilled  time.Time
+	remaining int // from headers, decremented per reservation; -1 until known
+	reset     time.Time
+	blocked   time.Time // secondary limit: no requests before this
+	used      int       // requests reserved since start
+}
+
+func (b *rateBucket) rate() float64 { return float64(b.limit) / b.window.Seconds() }
+
+func (b *rateBucket) refill(now time.Time) {
+	b.tokens += now.Sub(b.refilled).Seconds() * b.rate()
+	// a burst of a tenth of the window keeps pacing smooth without idling
+	if burst := max(1, b.limit/10); b.tokens > float64(burst) {
+		b.tokens = float64(burst)
+	}
+	b.refilled = now
+}
+
+type rateLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*rateBucket
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{buckets: map[string]*rateBucket{}}
+}
+
+// Caller holds l.mu.
+func (l *rateLimiter) bucket(resource string) *rateBucket {
+	b, ok := l.buckets[resource]
+	if !ok {
+		d, known := bucketDefaults[resource]
+		if !known {
+			d = bucketDefaults[resourceCore]
+		}
+		b = &rateBucket{limit: d.limit, window: d.window, tokens: 1, refilled: time.Now(), remaining: -1}
+		l.buckets[resource] = b
+	}
+	return b
+}
+
+// resourceFor names the bucket a request to path draws from.
+func resourceFor(path string) string {
+	switch {
+	case strings.Contains(path, "/search/code"):
+		return resourceCodeSearch
+	case strings.Contains(path, "/search/"):
+		return resourceSearch
+	case strings.HasSuffix(path, "/graphql"):
+		return resourceGraphQL
+	default:
+		return resourceCore
+	}
+}
+
+// reserve takes one request from resource's bucket and returns how long the caller
+// must wait before sending it.
+func (l *rateLimiter) reserve(resource string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	b := l.bucket(resource)
+	b.refill(now)
+	b.tokens--
+	b.used++
+	wait := b.delay(now)
+	if b.remaining > 0 {
+		b.remaining--
+	}
+	return wait
+}
+
+// refund gives back a reservation GitHub did not charge for (a 304 revalidation).
+func (l *rateLimiter) refund(resource string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b := l.bucket(resource)
+	b.tokens++
+	b.used--
+}
+
+// peek reports, without reserving, how long a request to resource would wait and how
+// much budget is left (the limit when GitHub has not reported it yet).
+func (l *rateLimiter) peek(resource string) (time.Duration, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	b := l.bucket(resource)
+	b.refill(now)
+	b.tokens--
+	wait := b.delay(now)
+	b.tokens++
+	left := b.remaining
+	if left < 0 {
+		left = b.limit
+	}
+	return wait, left
+}
+
+// delay is how long a request reserved at now (already taken from tokens) must wait.
+func (b *rateBucket) delay(now time.Time) time.Duration {
+	var wait time.Duration
+	if b.tokens < 0 {
+		wait = time.Duration(-b.tokens / b.rate() * float64(time.Second))
+	}
+	if b.remaining == 0 && b.reset.After(now) {
+		// exhausted per the server: everything waits for the reset
+		if d := b.reset.Sub(now) + 500*time.Millisecond; d > wait {
+			wait = d
+		}
+	}
+	if d := b.blocked.Sub(now); d > wait {
+		wait = d
+	}
+	return wait
+}
+
+// wait blocks until a request to resource may be sent, or ctx is done.
+// Waits longer than a second are reported through emit, naming the credential.
+func (l *rateLimiter) wait(ctx context.Context, resource, credential string, emit func(DebugEvent)) error {
+	d := l.reserve(resource)
+	if d <= 0 {
+		return nil
+	}
+	if d > time.Second && emit != nil {
+		emit(DebugEvent{Phase: "rate-wait", Note: credential + " " + resource + " " + d.Round(time.Second).String()})
+	}
+	return sleepCtx(ctx, d)
+}
+
+// update re-syncs the bucket named by resp's X-RateLimit-Resource and applies
+// secondary limits (Retry-After, or a 403/429 with budget left).
+func (l *rateLimiter) update(resource string, resp *http.Response) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if r := strings.ToLower(resp.Header.Get("X-RateLimit-Resource")); r != "" {
+		resource = r
+	}
+	b := l.bucket(resource)
+	if n, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Limit")); err == nil && n > 0 {
+		b.limit = n
+	}
+	rem, remErr := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	if remErr == nil {
+		b.remaining = rem
+	}
+	if u, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		b.reset = time.Unix(u, 0)
+	}
+	if ra := strings.TrimSpace(resp.Header.Get("Retry-After")); ra != "" {
+		if secs, err := strconv.Atoi(ra); err == nil && secs > 0 {
+			b.blocked = now.Add(time.Duration(secs)*time.Second + 500*time.Millisecond)
+		}
+		return
+	}
+	if (resp.StatusCode == 403 || resp.StatusCode == 429) && remErr == nil && rem > 0 {
+		b.blocked = now.Add(secondaryLimitPause)
+	}
+}
+
+// rateBudget is one bucket as reported by /api/status.
+type rateBudget struct {
+	APIBase    string `json:"apiBase"`
+	Credential string `json:"credential"` // token variable name, "GITHUB_TOKENS#n" or "app <id>"
+	Resource   string `json:"resource"`
+	Limit      int    `json:"limit"`
+	Remaining  int    `json:"remaining"` // -1 until GitHub has reported it
+	Reset      string `json:"reset,omitempty"`
+	Blocked    string `json:"blockedUntil,omitempty"`
+	Used       int    `json:"used"`
+}
+
+func (l *rateLimiter) snapshot() []rateBudget {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := make([]rateBudget, 0, len(l.buckets))
+	for name, b := range l.buckets {
+		rb := rateBudget{Resource: name, Limit: b.limit, Remaining: b.remaining, Used: b.used, Reset: fmtTime(b.reset)}
+		if b.blocked.After(time.Now()) {
+			rb.Blocked = fmtTime(b.blocked)
+		}
+		out = append(out, rb)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Resource < out[j].Resource })
+	return out
+}
@@ -0,0 +1,127 @@
+// repometa.go
+// Repository metadata for code hits: stars, forks, license, topics, archived/fork flags,
+// dates and owner type, fetched once per repository per run (and revalidated through the
+// HTTP cache across runs) so the report can tell a toy from a production project.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+type RepoMeta struct {
+	Stars     int       `json:"stars"`
+	Forks     int       `json:"forks"`
+	License   string    `json:"license,omitempty"` // SPDX id, e.g. MIT
+	Topics    []string  `json:"topics,omitempty"`
+	Archived  bool      `json:"archived"`
+	Fork      bool      `json:"fork"`
+	OwnerType string    `json:"ownerType"` // "User" or "Organization"
+	CreatedAt time.Time `json:"createdAt"`
+	PushedAt  time.Time `json:"pushedAt"`
+}
+
+type repoResp struct {
+	StargazersCount int      `json:"stargazers_count"`
+	ForksCount      int      `json:"forks_count"`
+	Topics          []string `json:"topics"`
+	Archived        bool     `json:"archived"`
+	Fork            bool     `json:"fork"`
+	CreatedAt       string   `json:"created_at"`
+	PushedAt        string   `json:"pushed_at"`
+	License         *struct {
+		SPDXID string `json:"spdx_id"`
+	} `json:"license"`
+	Owner struct {
+		Type string `json:"type"`
+	} `json:"owner"`
+}
+
+// enrichWithRepoMeta sets RepoMeta (and RepoPushed) on each hit, looking every
+// repository up once on the host clientFor picks for it. Repositories that can't be
+// fetched leave their hits without metadata.
+func enrichWithRepoMeta(ctx context.Context, clientFor func(CodeHit) *ghClient, hits []CodeHit, emit func(DebugEvent)) []CodeHit {
+	type job struct {
+		key, repo string
+		c         *ghClient
+	}
+	var jobs []job
+	seen := map[string]bool{}
+	for _, h := range hits {
+		c := clientFor(h)
+		key := c.apiBase + " " + h.Repository
+		if !seen[key] {
+			seen[key] = true
+			jobs = append(jobs, job{key, h.Repository, c})
+		}
+	}
+	emit(DebugEvent{Phase: "repo-meta", Note: fmt.Sprintf("repos=%d files=%d", len(jobs), len(hits))})
+
+	var mu sync.Mutex
+	metas := map[string]*RepoMeta{}
+	queue := make(chan job)
+	wg := sync.WaitGroup{}
+	worker := func() {
+		defer wg.Done()
+		for j := range queue {
+			m, err := fetchRepoMeta(ctx, j.c, j.repo)
+			if err != nil {
+				if ctx.Err() == nil {
+					emit(DebugEvent{Phase: "repo-meta-error", URL: j.c.repoURL(j.repo), Note: err.Error()})
+				}
+				continue
+			}
+			mu.Lock()
+			metas[j.key] = m
+			mu.Unlock()
+		}
+	}
+	wg.Add(maxConcurrentDetails)
+	for k := 0; k < maxConcurrentDetails; k++ {
+		go worker()
+	}
+	for _, j := range jobs {
+		queue <- j
+	}
+	close(queue)
+	wg.Wait()
+
+	out := make([]CodeHit, len(hits))
+	copy(out, hits)
+	for i := range out {
+		if m := metas[clientFor(out[i]).apiBase+" "+out[i].Repository]; m != nil {
+			out[i].RepoMeta = m
+			out[i].RepoPushed = m.PushedAt
+		}
+	}
+	emit(DebugEvent{Phase: "repo-meta-done", Note: fmt.Sprintf("found=%d", len(metas))})
+	return out
+}
+
+func fetchRepoMeta(ctx context.Context, c *ghClient, repo string) (*RepoMeta, error) {
+	resp, err := c.get(ctx, c.repoURL(repo))
+	if err != nil {
+		return nil, err
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("status %d: %s", resp.StatusCode, truncate(string(body), 200))
+	}
+	var r repoResp
+	if err := json.Unmarshal(body, &r); err != nil {
+		return nil, err
+	}
+	m := &RepoMeta{Stars: r.StargazersCount, Forks: r.ForksCount, Topics: r.Topics, Archived: r.Archived, Fork: r.Fork, OwnerType: r.Owner.Type}
+	if r.License != nil && r.License.SPDXID != "NOASSERTION" {
+		m.License = r.License.SPDXID
+	}
+	m.CreatedAt, _ = time.Parse(time.RFC3339, r.CreatedAt)
+	m.PushedAt, _ = time.Parse(time.RFC3339, r.PushedAt)
+	return m, nil
+}
@@ -0,0 +1,87 @@
+// retry.go
+// Retry policy for GitHub requests: network errors, 502/503/504 and secondary-limit
+// 403/429s are retried with jittered exponential backoff, drawing on a per-run budget
+// so a flaky network can't stretch a run indefinitely.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	maxAttempts    = 4  // per request, first try included
+	retryBudgetRun = 30 // retries shared by every request of one run
+	retryBaseDelay = time.Second
+	retryMaxDelay  = 30 * time.Second
+)
+
+// errGaveUp wraps a transport error that was still failing when the request ran out of
+// attempts or the run ran out of retries. Searches note it and move on to the next query.
+var errGaveUp = errors.New("gave up after retries")
+
+// retryBudget counts down the retries a run may still make.
+type retryBudget struct {
+	left atomic.Int64
+}
+
+func newRetryBudget(n int) *retryBudget {
+	b := &retryBudget{}
+	b.left.Store(int64(n))
+	return b
+}
+
+func (b *retryBudget) take() bool {
+	return b.left.Add(-1) >= 0
+}
+
+// retryable reports whether a response or transport error is worth another attempt.
+// Errors from the caller's own context (cancel, run budget) are not.
+func retryable(ctx context.Context, resp *http.Response, err error) bool {
+	if err != nil {
+		return ctx.Err() == nil && !errors.Is(err, context.Canceled)
+	}
+	switch resp.StatusCode {
+	case 502, 503, 504, 429:
+		return true
+	case 403:
+		return isSecondaryLimit(resp)
+	}
+	return false
+}
+
+// isSecondaryLimit tells a secondary (abuse) rate limit from a plain permission or
+// primary-limit 403: GitHub sends Retry-After, or leaves primary budget remaining.
+func isSecondaryLimit(resp *http.Response) bool {
+	if resp.Header.Get("Retry-After") != "" {
+		return true
+	}
+	rem, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	return err == nil && rem > 0
+}
+
+// backoff is full-jitter exponential: a random delay in [0, base*2^attempt), capped.
+func backoff(attempt int) time.Duration {
+	d := retryBaseDelay << attempt
+	if d > retryMaxDelay || d <= 0 {
+		d = retryMaxDelay
+	}
+	return time.Duration(rand.Int63n(int64(d))) + 100*time.Millisecond
+}
+
+func retryNote(attempt int, wait time.Duration, resp *http.Response, err error) string {
+	var cause string
+	if err != nil {
+		cause = err.Error()
+	} else {
+		cause = resp.Status
+	}
+	return fmt.Sprintf("attempt %d/%d failed (%s); retrying in %s", attempt, maxAttempts, cause, wait.Round(100*time.Millisecond))
+}
@@ -0,0 +1,241 @@
+// scheduler.go
+// Recurring runs inside `serve`: standard 5-field cron expressions (minute hour day-of-month
+// month day-of-week, local time) plus the @hourly/@daily/@weekly/@monthly shortcuts.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type cronSpec struct {
+	expr                     string
+	minute, hour, dom, month uint64 // bitsets
+	dow                      uint64
+	domStar, dowStar         bool
+}
+
+var cronShortcuts = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+}
+
+func parseCron(expr string) (*cronSpec, error) {
+	expr = strings.TrimSpace(expr)
+	fieldsExpr := expr
+	if sc, ok := cronShortcuts[strings.ToLower(expr)]; ok {
+		fieldsExpr = sc
+	}
+	f := strings.Fields(fieldsExpr)
+	if len(f) != 5 {
+		return nil, fmt.Errorf("cron %q: want 5 fields (min hour dom month dow), got %d", expr, len(f))
+	}
+	c := &cronSpec{expr: expr}
+	var err error
+	if c.minute, _, err = parseCronField(f[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("cron %q: minute: %w", expr, err)
+	}
+	if c.hour, _, err = parseCronField(f[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("cron %q: hour: %w", expr, err)
+	}
+	if c.dom, c.domStar, err = parseCronField(f[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("cron %q: day-of-month: %w", expr, err)
+	}
+	if c.month, _, err = parseCronField(f[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("cron %q: month: %w", expr, err)
+	}
+	if c.dow, c.dowStar, err = parseCronField(f[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("cron %q: day-of-week: %w", expr, err)
+	}
+	if c.dow&(1<<7) != 0 { // 7 is Sunday too
+		c.dow |= 1
+	}
+	return c, nil
+}
+
+// parseCronField handles "*", "n", "a-b", lists and "/step" on any of those. star is
+// true only for a bare "*": a stepped "*/n" restricts the field, so in day-of-month and
+// day-of-week it takes part in cron's either-may-match rule.
+func parseCronField(s string, lo, hi int) (bits uint64, star bool, err error) {
+	star = s == "*"
+	for _, part := range strings.Split(s, ",") {
+		rng, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n < 1 {
+				return 0, false, fmt.Errorf("bad step in %q", part)
+			}
+			rng, step = part[:i], n
+		}
+		from, to := lo, hi
+		switch {
+		case rng == "*":
+		case strings.Contains(rng, "-"):
+			ab := strings.SplitN(rng, "-", 2)
+			a, err1 := strconv.Atoi(ab[0])
+			b, err2 := strconv.Atoi(ab[1])
+			if err1 != nil || err2 != nil {
+				return 0, false, fmt.Errorf("bad range %q", rng)
+			}
+			from, to = a, b
+		default:
+			n, err := strconv.Atoi(rng)
+			if err != nil {
+				return 0, false, fmt.Errorf("bad value %q", rng)
+			}
+			from, to = n, n
+			if step > 1 {
+				to = hi
+			}
+		}
+		if from < lo || to > hi || from > to {
+			return 0, false, fmt.Errorf("%q out of range %d-%d", part, lo, hi)
+		}
+		for v := from; v <= to; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, star, nil
+}
+
+func (c *cronSpec) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	// cron semantics: when both are restricted, either may match
+	if !c.domStar && !c.dowStar {
+		return dom || dow
+	}
+	return dom && dow
+}
+
+// next returns the first matching minute strictly after t, or zero if none within ~5 years.
+func (c *cronSpec) next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func parseSchedules(exprs []string) ([]*cronSpec, error) {
+	var out []*cronSpec
+	for _, e := range exprs {
+		if strings.TrimSpace(e) == "" {
+			continue
+		}
+		c, err := parseCron(e)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+// nextScheduled picks the earliest upcoming fire time across specs.
+func nextScheduled(specs []*cronSpec, after time.Time) (time.Time, *cronSpec) {
+	var best time.Time
+	var which *cronSpec
+	for _, c := range specs {
+		if n := c.next(after); !n.IsZero() && (best.IsZero() || n.Before(best)) {
+			best, which = n, c
+		}
+	}
+	return best, which
+}
+
+// runScheduler fires scheduled runs until ctx is done. s.schedWake re-reads the
+// schedules after settings are saved.
+func (s *Server) runScheduler(ctx context.Context) {
+	for {
+		s.mu.RLock()
+		exprs := append([]string(nil), s.cfg.Schedules...)
+		s.mu.RUnlock()
+		specs, err := parseSchedules(exprs)
+		if err != nil {
+			log.Printf("scheduler: %v", err)
+		}
+		next, which := nextScheduled(specs, time.Now())
+
+		s.mu.Lock()
+		s.sched.next = next
+		s.sched.nextExpr = ""
+		if which != nil {
+			s.sched.nextExpr = which.expr
+		}
+		s.mu.Unlock()
+
+		var fire <-chan time.Time
+		var timer *time.Timer
+		if !next.IsZero() {
+			timer = time.NewTimer(time.Until(next))
+			fire = timer.C
+		}
+		select {
+		case <-ctx.Done():
+			stopTimer(timer)
+			return
+		case <-s.schedWake:
+			stopTimer(timer)
+		case <-fire:
+			s.fireScheduled(which.expr)
+		}
+	}
+}
+
+func (s *Server) fireScheduled(expr string) {
+	trigger := "schedule " + expr
+	cfg, spec, err := s.runnable()
+	if err != nil {
+		log.Printf("scheduler: skipping %s: %v", expr, err)
+		return
+	}
+	res, err := s.startRun(cfg, spec, trigger)
+	if err != nil {
+		log.Printf("scheduler: skipping %s: %v", expr, err)
+		return
+	}
+	s.mu.Lock()
+	s.sched.last = time.Now() // res.Started stays zero while the run is queued
+	s.sched.lastRunID = res.ID
+	s.mu.Unlock()
+}
+
+// wakeScheduler makes the scheduler pick up changed schedules.
+func (s *Server) wakeScheduler() {
+	select {
+	case s.schedWake <- struct{}{}:
+	default:
+	}
+}
+
+func stopTimer(t *time.Timer) {
+	if t != nil {
+		t.Stop()
+	}
+}
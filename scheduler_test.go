@@ -0,0 +1,80 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCronErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"@yearly",
+	} {
+		if _, err := parseCron(expr); err == nil {
+			t.Errorf("parseCron(%q): want error", expr)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	// 2026-03-04 is a Wednesday
+	at := func(s string) time.Time {
+		tm, err := time.ParseInLocation("2006-01-02 15:04", s, time.Local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tm
+	}
+	tests := []struct {
+		expr, after, want string
+	}{
+		{"*/15 * * * *", "2026-03-04 10:07", "2026-03-04 10:15"},
+		{"*/15 * * * *", "2026-03-04 10:45", "2026-03-04 11:00"},
+		{"0 9 * * 1-5", "2026-03-06 09:00", "2026-03-09 09:00"}, // Friday after the run → Monday
+		{"30 6 1 * *", "2026-03-04 00:00", "2026-04-01 06:30"},
+		{"0 0 * * 7", "2026-03-04 12:00", "2026-03-08 00:00"}, // 7 is Sunday
+		{"@weekly", "2026-03-04 12:00", "2026-03-08 00:00"},
+		{"@hourly", "2026-03-04 12:00", "2026-03-04 13:00"},
+		{"0 8 5,20 * *", "2026-03-05 08:00", "2026-03-20 08:00"},
+		{"0 0 29 2 *", "2026-03-01 00:00", "2028-02-29 00:00"},
+		// day-of-month and day-of-week both restricted: either may match
+		{"0 0 13 * 5", "2026-03-04 00:00", "2026-03-06 00:00"},
+		{"0 0 13 * 5", "2026-03-07 00:00", "2026-03-13 00:00"},
+		// a stepped "*/n" restricts the field too
+		{"0 0 */10 * 1", "2026-03-04 00:00", "2026-03-09 00:00"},
+		{"0 0 */10 * 1", "2026-03-09 00:00", "2026-03-11 00:00"},
+		{"0 0 1 * */3", "2026-03-04 00:00", "2026-03-07 00:00"}, // Sun, Wed, Sat
+		// only one restricted: it alone decides
+		{"0 0 * * 1", "2026-03-04 00:00", "2026-03-09 00:00"},
+		{"0 0 15 * *", "2026-03-04 00:00", "2026-03-15 00:00"},
+	}
+	for _, tt := range tests {
+		c, err := parseCron(tt.expr)
+		if err != nil {
+			t.Errorf("parseCron(%q): %v", tt.expr, err)
+			continue
+		}
+		if got := c.next(at(tt.after)); !got.Equal(at(tt.want)) {
+			t.Errorf("%q after %s = %s, want %s", tt.expr, tt.after, got.Format("2006-01-02 15:04 Mon"), tt.want)
+		}
+	}
+}
+
+func TestCronNextNever(t *testing.T) {
+	c, err := parseCron("0 0 31 2 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.next(time.Now()); !got.IsZero() {
+		t.Errorf("Feb 31 fires at %s, want never", got)
+	}
+}
@@ -0,0 +1,175 @@
+// scoring.go
+// Relevance scores for findings. Each signal maps a hit to 0..1, the score is
+// the weighted sum, and hits are ranked by it so the report and the OpenAI payload cap
+// keep the strongest matches rather than the merely most recent. Weights come from
+// settings (AppSettings.Scoring); add a signal by appending to scoreSignals.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ScoreWeights weighs each signal; 0 turns a signal off.
+type ScoreWeights struct {
+	Recency float64 `json:"recency"` // last activity: 1 today, 0 at the window's start
+	Stars   float64 `json:"stars"`   // log scale, 1 at 10k stars (repo hits, and code hits with repo metadata)
+	Queries float64 `json:"queries"` // other queries matching the same item: 1/3 each, up to 3
+	Path    float64 `json:"path"`    // subtracted for test/vendor/example/generated paths
+	Org     float64 `json:"org"`     // repository owned by an organization
+}
+
+func defaultScoreWeights() ScoreWeights {
+	return ScoreWeights{Recency: 1, Stars: 1, Queries: 1, Path: 1, Org: 0.25}
+}
+
+// scoreInput is what the signals see of any kind of hit.
+type scoreInput struct {
+	when      time.Time // last activity, zero if unknown
+	stars     int       // -1 if unknown
+	queries   int       // distinct queries that matched the item
+	path      string    // file path, code hits only
+	ownerType string    // "User" / "Organization", "" if unknown
+}
+
+type scoreSignal struct {
+	name   string
+	weight func(ScoreWeights) float64
+	value  func(in scoreInput, since, now time.Time) float64
+}
+
+// noisePath matches paths whose hits are rarely real adoption: tests, vendored or
+// generated code, examples and docs.
+var noisePath = regexp.MustCompile(`(?i)(^|/)(tests?|__tests__|spec|vendor|node_modules|third_party|examples?|samples?|docs?|dist|build|generated)(/|$)|_test\.|\.test\.|\.spec\.|\.min\.js$`)
+
+var scoreSignals = []scoreSignal{
+	{"recency", func(w ScoreWeights) float64 { return w.Recency }, func(in scoreInput, since, now time.Time) float64 {
+		if in.when.IsZero() || !now.After(since) {
+			return 0
+		}
+		return clamp01(1 - now.Sub(in.when).Seconds()/now.Sub(since).Seconds())
+	}},
+	{"stars", func(w ScoreWeights) float64 { return w.Stars }, func(in scoreInput, _, _ time.Time) float64 {
+		if in.stars < 0 {
+			return 0
+		}
+		return clamp01(math.Log10(float64(in.stars)+1) / 4)
+	}},
+	{"queries", func(w ScoreWeights) float64 { return w.Queries }, func(in scoreInput, _, _ time.Time) float64 {
+		return float64(min(max(in.queries-1, 0), 3)) / 3
+	}},
+	{"path", func(w ScoreWeights) float64 { return -w.Path }, func(in scoreInput, _, _ time.Time) float64 {
+		if in.path != "" && noisePath.MatchString(in.path) {
+			return 1
+		}
+		return 0
+	}},
+	{"org", func(w ScoreWeights) float64 { return w.Org }, func(in scoreInput, _, _ time.Time) float64 {
+		if in.ownerType == "Organization" {
+			return 1
+		}
+		return 0
+	}},
+}
+
+func (w ScoreWeights) score(in scoreInput, since, now time.Time) float64 {
+	total := 0.0
+	for _, s := range scoreSignals {
+		if wt := s.weight(w); wt != 0 {
+			total += wt * s.value(in, since, now)
+		}
+	}
+	if r := math.Round(total*100) / 100; r != 0 {
+		return r
+	}
+	return 0 // not -0 in JSON
+}
+
+func clamp01(x float64) float64 { return math.Max(0, math.Min(1, x)) }
+
+// rankFindings scores every (merged) hit of f and sorts each list by score, most recent
+// first on ties.
+func rankFindings(f *Findings, w ScoreWeights, since time.Time) {
+	now := time.Now()
+	for i := range f.CodeHits {
+		h := &f.CodeHits[i]
+		in := scoreInput{when: h.CommitDate, stars: -1, queries: len(h.MatchedBy), path: h.FilePath}
+		if in.when.IsZero() {
+			in.when = h.RepoPushed
+		}
+		if m := h.RepoMeta; m != nil {
+			in.stars, in.ownerType = m.Stars, m.OwnerType
+		}
+		h.Score = w.score(in, since, now)
+	}
+	for i := range f.RepoHits {
+		h := &f.RepoHits[i]
+		h.Score = w.score(scoreInput{when: h.PushedAt, stars: h.Stars, queries: len(h.MatchedBy)}, since, now)
+	}
+	for i := range f.CommitHits {
+		h := &f.CommitHits[i]
+		h.Score = w.score(scoreInput{when: h.CommittedAt, stars: -1, queries: len(h.MatchedBy)}, since, now)
+	}
+	for i := range f.IssueHits {
+		h := &f.IssueHits[i]
+		h.Score = w.score(scoreInput{when: h.UpdatedAt, stars: -1, queries: len(h.MatchedBy)}, since, now)
+	}
+
+	byScore := func(si, sj float64, ti, tj time.Time) bool {
+		if si != sj {
+			return si > sj
+		}
+		return ti.After(tj)
+	}
+	codeWhen := func(h CodeHit) time.Time {
+		if h.CommitDate.IsZero() {
+			return h.RepoPushed
+		}
+		return h.CommitDate
+	}
+	sort.SliceStable(f.CodeHits, func(i, j int) bool {
+		a, b := f.CodeHits[i], f.CodeHits[j]
+		return byScore(a.Score, b.Score, codeWhen(a), codeWhen(b))
+	})
+	sort.SliceStable(f.RepoHits, func(i, j int) bool {
+		a, b := f.RepoHits[i], f.RepoHits[j]
+		return byScore(a.Score, b.Score, a.PushedAt, b.PushedAt)
+	})
+	sort.SliceStable(f.CommitHits, func(i, j int) bool {
+		a, b := f.CommitHits[i], f.CommitHits[j]
+		return byScore(a.Score, b.Score, a.CommittedAt, b.CommittedAt)
+	})
+	sort.SliceStable(f.IssueHits, func(i, j int) bool {
+		a, b := f.IssueHits[i], f.IssueHits[j]
+		return byScore(a.Score, b.Score, a.UpdatedAt, b.UpdatedAt)
+	})
+}
+
+// parseScoreWeights applies "recency=1,stars=0.5,..." onto w; unnamed weights keep
+// their value.
+func parseScoreWeights(s string, w *ScoreWeights) error {
+	fields := map[string]*float64{"recency": &w.Recency, "stars": &w.Stars, "queries": &w.Queries, "path": &w.Path, "org": &w.Org}
+	for _, part := range splitList(s, ",") {
+		k, v, ok := strings.Cut(part, "=")
+		p := fields[strings.ToLower(strings.TrimSpace(k))]
+		if !ok || p == nil {
+			return fmt.Errorf("bad weight %q (want name=number; names: recency, stars, queries, path, org)", part)
+		}
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return fmt.Errorf("bad weight %q: %w", part, err)
+		}
+		*p = f
+	}
+	return nil
+}
+
+func (w ScoreWeights) String() string {
+	return fmt.Sprintf("recency=%g,stars=%g,queries=%g,path=%g,org=%g", w.Recency, w.Stars, w.Queries, w.Path, w.Org)
+}
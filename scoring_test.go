@@ -0,0 +1,81 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseScoreWeights(t *testing.T) {
+	def := defaultScoreWeights()
+	tests := []struct {
+		in      string
+		want    ScoreWeights
+		wantErr bool
+	}{
+		{"", def, false},
+		{"stars=0", ScoreWeights{Recency: 1, Stars: 0, Queries: 1, Path: 1, Org: 0.25}, false},
+		{" Recency = 2 , org=0.5", ScoreWeights{Recency: 2, Stars: 1, Queries: 1, Path: 1, Org: 0.5}, false},
+		{"recency=1,stars=0.5,queries=0,path=2,org=-1", ScoreWeights{Recency: 1, Stars: 0.5, Queries: 0, Path: 2, Org: -1}, false},
+		{"forks=1", def, true},
+		{"stars", def, true},
+		{"stars=lots", def, true},
+	}
+	for _, tt := range tests {
+		w := defaultScoreWeights()
+		err := parseScoreWeights(tt.in, &w)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseScoreWeights(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && w != tt.want {
+			t.Errorf("parseScoreWeights(%q) = %+v, want %+v", tt.in, w, tt.want)
+		}
+	}
+	// String round-trips
+	w := ScoreWeights{Recency: 0.5, Stars: 2, Queries: 0, Path: 1, Org: 0.25}
+	var got ScoreWeights
+	if err := parseScoreWeights(w.String(), &got); err != nil || got != w {
+		t.Errorf("round trip of %q = %+v, %v", w.String(), got, err)
+	}
+}
+
+func TestScoreSignalsInRange(t *testing.T) {
+	now := time.Now()
+	since := now.AddDate(0, 0, -30)
+	only := func(w ScoreWeights, in scoreInput) float64 { return w.score(in, since, now) }
+	tests := []struct {
+		name string
+		w    ScoreWeights
+		in   scoreInput
+		want float64
+	}{
+		{"queries one", ScoreWeights{Queries: 1}, scoreInput{queries: 1, stars: -1}, 0},
+		{"queries two", ScoreWeights{Queries: 1}, scoreInput{queries: 2, stars: -1}, 0.33},
+		{"queries capped", ScoreWeights{Queries: 1}, scoreInput{queries: 9, stars: -1}, 1},
+		{"stars 10k", ScoreWeights{Stars: 1}, scoreInput{stars: 9999}, 1},
+		{"stars unknown", ScoreWeights{Stars: 1}, scoreInput{stars: -1}, 0},
+		{"recency today", ScoreWeights{Recency: 1}, scoreInput{when: now, stars: -1}, 1},
+		{"recency at since", ScoreWeights{Recency: 1}, scoreInput{when: since, stars: -1}, 0},
+		{"noise path", ScoreWeights{Path: 1}, scoreInput{path: "src/vendor/x.go", stars: -1}, -1},
+		{"org", ScoreWeights{Org: 1}, scoreInput{ownerType: "Organization", stars: -1}, 1},
+	}
+	for _, tt := range tests {
+		if got := only(tt.w, tt.in); got != tt.want {
+			t.Errorf("%s: score = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRankFindingsRepoStars(t *testing.T) {
+	now := time.Now()
+	pushed := now.AddDate(0, 0, -1)
+	f := Findings{RepoHits: []RepoHit{
+		{FullName: "a/few", PushedAt: pushed, Stars: 3},
+		{FullName: "a/many", PushedAt: pushed, Stars: 5000},
+	}}
+	rankFindings(&f, ScoreWeights{Recency: 1, Stars: 1}, now.AddDate(0, 0, -30))
+	if f.RepoHits[0].FullName != "a/many" || f.RepoHits[0].Score <= f.RepoHits[1].Score {
+		t.Errorf("ranked %s (%v) before %s (%v), want the starred repo first",
+			f.RepoHits[0].FullName, f.RepoHits[0].Score, f.RepoHits[1].FullName, f.RepoHits[1].Score)
+	}
+}
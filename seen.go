@@ -0,0 +1,223 @@
+// seen.go
+// Persistent "seen hits" store: remembers every code/repo hit across runs so a
+// report can tell genuinely new findings from ones we've already reported.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const defaultSeenFile = "seen.json"
+
+type seenEntry struct {
+	FirstSeen time.Time `json:"firstSeen"`
+	LastSeen  time.Time `json:"lastSeen"`
+}
+
+type seenStore struct {
+	path   string
+	Code   map[string]seenEntry `json:"code"`
+	Repo   map[string]seenEntry `json:"repo"`
+	Commit map[string]seenEntry `json:"commit"`
+	Issue  map[string]seenEntry `json:"issue"`
+}
+
+// seenMu serializes load/mark/save so overlapping runs don't lose each other's entries.
+var seenMu sync.Mutex
+
+func loadSeenStore(path string) (*seenStore, error) {
+	st := &seenStore{path: path, Code: map[string]seenEntry{}, Repo: map[string]seenEntry{}, Commit: map[string]seenEntry{}, Issue: map[string]seenEntry{}}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return st, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(b, st); err != nil {
+		return nil, err
+	}
+	if st.Code == nil {
+		st.Code = map[string]seenEntry{}
+	}
+	if st.Repo == nil {
+		st.Repo = map[string]seenEntry{}
+	}
+	if st.Commit == nil {
+		st.Commit = map[string]seenEntry{}
+	}
+	if st.Issue == nil {
+		st.Issue = map[string]seenEntry{}
+	}
+	return st, nil
+}
+
+func (st *seenStore) save() error {
+	b, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	// write-then-rename so a crash never leaves a half-written store behind
+	tmp := st.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, st.path)
+}
+
+// mark stamps first/last-seen on every hit in f and flags the ones never seen before.
+func (st *seenStore) mark(f *Findings, now time.Time) {
+	for i := range f.CodeHits {
+		h := &f.CodeHits[i]
+		h.FirstSeen, h.LastSeen, h.IsNew = touchSeen(st.Code, codeKey(*h), now)
+	}
+	for i := range f.RepoHits {
+		h := &f.RepoHits[i]
+		h.FirstSeen, h.LastSeen, h.IsNew = touchSeen(st.Repo, repoKey(*h), now)
+	}
+	for i := range f.CommitHits {
+		h := &f.CommitHits[i]
+		h.FirstSeen, h.LastSeen, h.IsNew = touchSeen(st.Commit, commitKey(*h), now)
+	}
+	for i := range f.IssueHits {
+		h := &f.IssueHits[i]
+		h.FirstSeen, h.LastSeen, h.IsNew = touchSeen(st.Issue, issueKey(*h), now)
+	}
+}
+
+func touchSeen(m map[string]seenEntry, key string, now time.Time) (first, last time.Time, isNew bool) {
+	e, ok := m[key]
+	if !ok {
+		e.FirstSeen = now
+	}
+	e.LastSeen = now
+	m[key] = e
+	return e.FirstSeen, e.LastSeen, !ok
+}
+
+// markSeen stamps f against the store at path without writing it: hits only count as
+// seen once a report about them exists (see recordSeen).
+func markSeen(path string, f *Findings) error {
+	seenMu.Lock()
+	defer seenMu.Unlock()
+	st, err := loadSeenStore(seenPath(path))
+	if err != nil {
+		return err
+	}
+	st.mark(f, time.Now().UTC())
+	return nil
+}
+
+// recordSeen writes the stamps markSeen put on f into the store at path. The store is
+// re-read first, so entries from runs that finished in between are kept.
+func recordSeen(path string, f Findings) error {
+	path = seenPath(path)
+	seenMu.Lock()
+	defer seenMu.Unlock()
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	st, err := loadSeenStore(path)
+	if err != nil {
+		return err
+	}
+	for _, h := range f.CodeHits {
+		recordEntry(st.Code, codeKey(h), h.FirstSeen, h.LastSeen)
+	}
+	for _, h := range f.RepoHits {
+		recordEntry(st.Repo, repoKey(h), h.FirstSeen, h.LastSeen)
+	}
+	for _, h := range f.CommitHits {
+		recordEntry(st.Commit, commitKey(h), h.FirstSeen, h.LastSeen)
+	}
+	for _, h := range f.IssueHits {
+		recordEntry(st.Issue, issueKey(h), h.FirstSeen, h.LastSeen)
+	}
+	return st.save()
+}
+
+func recordEntry(m map[string]seenEntry, key string, first, last time.Time) {
+	e, ok := m[key]
+	if !ok || first.Before(e.FirstSeen) {
+		e.FirstSeen = first
+	}
+	if last.After(e.LastSeen) {
+		e.LastSeen = last
+	}
+	m[key] = e
+}
+
+func seenPath(path string) string {
+	if path == "" {
+		return defaultSeenFile
+	}
+	return path
+}
+
+// onlyNew returns a copy of f restricted to first-seen hits.
+func onlyNew(f Findings) Findings {
+	out := f
+	out.CodeHits = nil
+	for _, h := range f.CodeHits {
+		if h.IsNew {
+			out.CodeHits = append(out.CodeHits, h)
+		}
+	}
+	out.RepoHits = nil
+	for _, h := range f.RepoHits {
+		if h.IsNew {
+			out.RepoHits = append(out.RepoHits, h)
+		}
+	}
+	out.CommitHits = nil
+	for _, h := range f.CommitHits {
+		if h.IsNew {
+			out.CommitHits = append(out.CommitHits, h)
+		}
+	}
+	out.IssueHits = nil
+	for _, h := range f.IssueHits {
+		if h.IsNew {
+			out.IssueHits = append(out.IssueHits, h)
+		}
+	}
+	return out
+}
+
+// newCounts is how many hits of each kind were first seen in a run.
+type newCounts struct {
+	Code, Repo, Commit, Issue int
+}
+
+func countNew(f Findings) newCounts {
+	var n newCounts
+	for _, h := range f.CodeHits {
+		if h.IsNew {
+			n.Code++
+		}
+	}
+	for _, h := range f.RepoHits {
+		if h.IsNew {
+			n.Repo++
+		}
+	}
+	for _, h := range f.CommitHits {
+		if h.IsNew {
+			n.Commit++
+		}
+	}
+	for _, h := range f.IssueHits {
+		if h.IsNew {
+			n.Issue++
+		}
+	}
+	return n
+}
@@ -0,0 +1,88 @@
+// snippets.go
+// Matching code for code hits. Code search is requested with the text-match media type,
+// so each item carries the fragments around the matched terms; they go into the report
+// as fenced snippets so it can show how an API is used, not just where.
+
+package main
+
+import (
+	"strings"
+)
+
+const (
+	maxSnippetsPerHit = 3   // fragments kept per file
+	llmSnippetLen     = 300 // characters of the first fragment sent to OpenAI
+)
+
+// CodeSnippet is one matched fragment: a few lines of the file around the match.
+type CodeSnippet struct {
+	Fragment string   `json:"fragment"`
+	Matches  []string `json:"matches,omitempty"` // the matched terms, as they appear in Fragment
+}
+
+// textMatch is one entry of a search item's text_matches.
+type textMatch struct {
+	Property string `json:"property"` // "content" for file text, "path" for the file path
+	Fragment string `json:"fragment"`
+	Matches  []struct {
+		Text string `json:"text"`
+	} `json:"matches"`
+}
+
+func codeHitFrom(it codeItem, g SearchGroup, q SearchQuery) CodeHit {
+	return CodeHit{
+		Group:      g.Name,
+		QueryName:  q.Name,
+		Repository: it.Repository.FullName,
+		RepoURL:    it.Repository.HTMLURL,
+		FilePath:   it.Path,
+		FileURL:    it.HTMLURL,
+		Language:   it.Repository.Language,
+		Snippets:   snippetsFrom(it.TextMatches),
+	}
+}
+
+// snippetsFrom keeps the content fragments of tm, without repeats.
+func snippetsFrom(tm []textMatch) []CodeSnippet {
+	var out []CodeSnippet
+	seen := map[string]bool{}
+	for _, m := range tm {
+		frag := strings.Trim(m.Fragment, "\n")
+		if m.Property != "content" || frag == "" || seen[frag] {
+			continue
+		}
+		seen[frag] = true
+		s := CodeSnippet{Fragment: frag}
+		for _, x := range m.Matches {
+			s.Matches = append(s.Matches, x.Text)
+		}
+		out = append(out, s)
+		if len(out) == maxSnippetsPerHit {
+			break
+		}
+	}
+	return out
+}
+
+func hasSnippet(list []CodeSnippet, s CodeSnippet) bool {
+	for _, x := range list {
+		if x.Fragment == s.Fragment {
+			return true
+		}
+	}
+	return false
+}
+
+// writeSnippet renders s as a fenced block indented under a list item. The fence is
+// longer than any backtick run in the fragment so it can't be closed early.
+func writeSnippet(b *strings.Builder, s CodeSnippet, lang string) {
+	fence := "```"
+	for strings.Contains(s.Fragment, fence) {
+		fence += "`"
+	}
+	b.WriteString("  " + fence + strings.ToLower(strings.ReplaceAll(lang, " ", "")) + "\n")
+	for _, line := range strings.Split(s.Fragment, "\n") {
+		b.WriteString("  " + line + "\n")
+	}
+	b.WriteString("  " + fence + "\n")
+}
@@ -0,0 +1,119 @@
+// window.go
+// Date-sliced searches for repo/commits/issues/pulls queries. GitHub search stops at
+// 1000 results per query, so a window whose total_count is over the cap is bisected
+// on its date qualifier until every slice fits (or is a single day).
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+const (
+	searchResultCap = 1000 // GitHub serves at most this many results per search query
+	maxWindowDepth  = 6    // at most 2^6 slices per query
+)
+
+// windowedSearch is one query run over date slices.
+type windowedSearch struct {
+	kind     string // debug phase infix: "repo", "commits", "issues" or "pulls"
+	endpoint string // <apiBase>/search/<endpoint>
+	sort     string
+	field    string // date qualifier to bisect (pushed, committer-date, updated); "" runs query as-is
+	query    string // the user's query plus any fixed qualifiers, without the window
+	// decode parses one page, collects its hits and reports total_count,
+	// incomplete_results and how many items the page held.
+	decode func(body []byte) (total int, incomplete bool, items int, err error)
+}
+
+// run pages through every slice of [since, today]. Non-200 pages end their slice with a
+// note; transport and decode errors abort. Each split extends the run's time budget by
+// the pages its halves may fetch.
+func (ws windowedSearch) run(ctx context.Context, client *ghClient, cfg AppSettings, g SearchGroup, q SearchQuery, since time.Time, emit func(DebugEvent)) ([]string, error) {
+	var notes []string
+	qName := fmt.Sprintf("%s — %s", g.Name, q.Name)
+	phase := "search-" + ws.kind
+
+	var walk func(from, to time.Time, depth int) error
+	walk = func(from, to time.Time, depth int) error {
+		query, window := ws.query, "query"
+		if ws.field != "" {
+			window = fmt.Sprintf("%s:%s..%s", ws.field, from.Format("2006-01-02"), to.Format("2006-01-02"))
+			query += " " + window
+		}
+		for page := 1; page <= cfg.MaxPages; page++ {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			url := client.searchURL(ws.endpoint, urlQueryEscape(query), ws.sort, cfg.PerPage, page)
+			emit(DebugEvent{Phase: phase, Group: g.Name, QueryName: q.Name, URL: url, Page: page})
+			resp, err := client.get(ctx, url)
+			if err != nil {
+				emit(DebugEvent{Phase: phase + "-error", Group: g.Name, QueryName: q.Name, URL: url, Page: page, Note: err.Error()})
+				return err
+			}
+			body, _ := io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+			if resp.StatusCode != 200 {
+				rlRem := resp.Header.Get("X-RateLimit-Remaining")
+				rlRes := resp.Header.Get("X-RateLimit-Reset")
+				note := truncate(string(body), 200)
+				if resp.StatusCode == 403 || rlRem == "0" {
+					if ru, err := strconv.ParseInt(rlRes, 10, 64); err == nil {
+						if secs := int(time.Until(time.Unix(ru, 0)).Seconds()); secs > 0 {
+							note = fmt.Sprintf("rate-limited; sleeping %ds; body=%s", secs, note)
+						}
+					}
+				}
+				notes = append(notes, fmt.Sprintf("(%s) status=%d remaining=%s reset=%s url=%s body=%s",
+					qName, resp.StatusCode, rlRem, rlRes, url, truncate(string(body), 400)))
+				emit(DebugEvent{Phase: phase + "-non200", Group: g.Name, QueryName: q.Name, URL: url, Page: page, Status: resp.StatusCode, RateRemaining: rlRem, RateReset: rlRes, Note: note})
+				return nil
+			}
+			// page 1 doubles as the size probe for this slice; a slice that is split
+			// is not decoded, its halves fetch their own first pages
+			if page == 1 {
+				var probe struct {
+					TotalCount int `json:"total_count"`
+				}
+				_ = json.Unmarshal(body, &probe)
+				splittable := ws.field != "" && to.After(from) && depth < maxWindowDepth
+				switch total := probe.TotalCount; {
+				case total > searchResultCap && splittable:
+					mid := from.AddDate(0, 0, int(to.Sub(from).Hours()/24)/2)
+					client.budget.addRequests(2 * cfg.MaxPages)
+					emit(DebugEvent{Phase: phase + "-split", Group: g.Name, QueryName: q.Name, Note: fmt.Sprintf("total=%d over cap; splitting %s at %s", total, window, mid.Format("2006-01-02"))})
+					if err := walk(from, mid, depth+1); err != nil {
+						return err
+					}
+					return walk(mid.AddDate(0, 0, 1), to, depth+1)
+				case total > searchResultCap:
+					notes = append(notes, fmt.Sprintf("(%s) %d results for %s exceed GitHub's %d-result cap; the rest are unreachable", qName, total, window, searchResultCap))
+				case total > cfg.MaxPages*cfg.PerPage:
+					notes = append(notes, fmt.Sprintf("(%s) %d results for %s; only the first %d are fetched (maxPages × perPage)", qName, total, window, cfg.MaxPages*cfg.PerPage))
+				}
+			}
+			total, incomplete, items, err := ws.decode(body)
+			if err != nil {
+				return err
+			}
+			emit(DebugEvent{Phase: phase + "-ok", Group: g.Name, QueryName: q.Name, URL: url, Page: page, Status: 200, Note: fmt.Sprintf("items=%d total=%d", items, total)})
+			if incomplete {
+				notes = append(notes, fmt.Sprintf("(%s) GitHub returned incomplete results (search timed out) for %s page %d", qName, window, page))
+			}
+			if items == 0 {
+				break
+			}
+		}
+		return nil
+	}
+
+	day := 24 * time.Hour
+	err := walk(since.UTC().Truncate(day), time.Now().UTC().Truncate(day), 0)
+	return notes, err
+}
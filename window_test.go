@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSearch serves /search/repositories with perDay results for every day of the
+// pushed:A..B window in the query; each item names its window.
+func fakeSearch(t *testing.T, perDay int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var window string
+		for _, f := range strings.Fields(r.URL.Query().Get("q")) {
+			if v, ok := strings.CutPrefix(f, "pushed:"); ok {
+				window = v
+			}
+		}
+		a, b, _ := strings.Cut(window, "..")
+		from, err1 := time.Parse("2006-01-02", a)
+		to, err2 := time.Parse("2006-01-02", b)
+		if err1 != nil || err2 != nil {
+			t.Errorf("bad window %q", window)
+			http.Error(w, "bad window", 422)
+			return
+		}
+		total := (int(to.Sub(from).Hours()/24) + 1) * perDay
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+		n := min(perPage, max(0, total-(page-1)*perPage))
+		items := make([]map[string]string, n)
+		for i := range items {
+			items[i] = map[string]string{"window": window}
+		}
+		w.Header().Set("X-RateLimit-Resource", "search")
+		w.Header().Set("X-RateLimit-Limit", "1000000")
+		w.Header().Set("X-RateLimit-Remaining", "1000000")
+		_ = json.NewEncoder(w).Encode(map[string]any{"total_count": total, "incomplete_results": false, "items": items})
+	}))
+}
+
+func TestWindowedSearchBisects(t *testing.T) {
+	tests := []struct {
+		name     string
+		days     int // window is [today-days, today]
+		perDay   int
+		maxPages int
+		slices   int    // windows decoded
+		note     string // substring of the only note, "" for none
+	}{
+		{"fits", 9, 10, 10, 1, ""},
+		{"over maxPages", 9, 50, 2, 1, "only the first 200 are fetched"},
+		{"split once", 29, 50, 10, 2, ""},
+		{"split twice", 29, 100, 10, 4, ""},
+		{"single day over cap", 0, 5000, 10, 1, "exceed GitHub's 1000-result cap"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := fakeSearch(t, tt.perDay)
+			defer srv.Close()
+			client := &ghClient{apiBase: srv.URL, creds: credentialsFor(srv.URL, []tokenSource{envToken("GHW_TEST_NO_TOKEN")}),
+				retries: newRetryBudget(retryBudgetRun), cache: newHTTPCache(t.TempDir()), usage: newTokenUsage(), emit: func(DebugEvent) {}}
+
+			decoded := map[string]int{} // window → items
+			ws := windowedSearch{kind: "repo", endpoint: "repositories", sort: "updated", field: "pushed", query: "polygon",
+				decode: func(body []byte) (int, bool, int, error) {
+					var sr struct {
+						TotalCount int                 `json:"total_count"`
+						Items      []map[string]string `json:"items"`
+					}
+					if err := json.Unmarshal(body, &sr); err != nil {
+						return 0, false, 0, err
+					}
+					for _, it := range sr.Items {
+						decoded[it["window"]]++
+					}
+					return sr.TotalCount, false, len(sr.Items), nil
+				}}
+			cfg := AppSettings{MaxPages: tt.maxPages, PerPage: 100}
+			since := time.Now().UTC().AddDate(0, 0, -tt.days)
+			notes, err := ws.run(context.Background(), client, cfg, SearchGroup{Name: "g"}, SearchQuery{Name: "q"}, since, func(DebugEvent) {})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(decoded) != tt.slices {
+				t.Errorf("decoded %d windows %v, want %d", len(decoded), decoded, tt.slices)
+			}
+			// the decoded windows tile [since, today] without gaps or overlaps
+			var windows []string
+			for w := range decoded {
+				windows = append(windows, w)
+			}
+			sort.Strings(windows)
+			day := 24 * time.Hour
+			next := since.Truncate(day)
+			for _, w := range windows {
+				a, b, _ := strings.Cut(w, "..")
+				if a != next.Format("2006-01-02") {
+					t.Errorf("window %s starts at %s, want %s", w, a, next.Format("2006-01-02"))
+				}
+				to, _ := time.Parse("2006-01-02", b)
+				next = to.AddDate(0, 0, 1)
+				if tt.note == "" && decoded[w] > searchResultCap {
+					t.Errorf("window %s decoded %d items, over the cap", w, decoded[w])
+				}
+			}
+			if today := time.Now().UTC().Truncate(day); !next.Equal(today.AddDate(0, 0, 1)) {
+				t.Errorf("windows end before %s: %v", today.Format("2006-01-02"), windows)
+			}
+
+			switch {
+			case tt.note == "" && len(notes) > 0:
+				t.Errorf("unexpected notes %q", notes)
+			case tt.note != "" && (len(notes) != 1 || !strings.Contains(notes[0], tt.note)):
+				t.Errorf("notes = %q, want one containing %q", notes, tt.note)
+			}
+		})
+	}
+}
+
+func TestRunBudgetAddRequests(t *testing.T) {
+	_, b, cancel := withRunBudget(context.Background(), time.Minute, 10*time.Second)
+	defer cancel()
+	start := b.deadline
+	b.addRequests(3)
+	if got := b.deadline.Sub(start); got != 30*time.Second {
+		t.Errorf("extended by %s, want 30s", got)
+	}
+	b.addRequests(1000)
+	if !b.deadline.Equal(b.limit) {
+		t.Errorf("deadline %s not capped at %s", b.deadline, b.limit)
+	}
+	var nilBudget *runBudget
+	nilBudget.addRequests(1) // no-op
+}